@@ -0,0 +1,5 @@
+package v1alpha1
+
+// Hub marks v1alpha1 as the version other versions of CarbonEstimator convert through. It is the
+// storage version and the one the controller reconciles.
+func (*CarbonEstimator) Hub() {}
@@ -2,13 +2,20 @@ package v1alpha1
 
 import (
 	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
 	"sustain_kube/internal/utils"
 )
 
-func (carbonEstimator *CarbonEstimator) UpdateStatus(consumption, emission float64) {
+func (carbonEstimator *CarbonEstimator) UpdateStatus(consumption, facilityConsumption, emission float64) {
 
 	carbonEstimator.Status.ErrorMessage = ""
 	carbonEstimator.Status.Consumption = strconv.FormatFloat(consumption, 'f', 2, 64)
+	carbonEstimator.Status.FacilityConsumption = strconv.FormatFloat(facilityConsumption, 'f', 2, 64)
 	carbonEstimator.Status.Emission = strconv.FormatFloat(emission, 'f', 2, 64)
 
 	if consumption > float64(carbonEstimator.Spec.CriticalLevel) {
@@ -21,11 +28,269 @@ func (carbonEstimator *CarbonEstimator) UpdateStatus(consumption, emission float
 
 }
 
-// Error sets the status of the CarbonEstimator to Error
-func (carbonEstimator *CarbonEstimator) Error(msg string) {
+// Error sets the status of the CarbonEstimator to Error. The last good measurements are left in place,
+// the condition of the failing step and the Ready condition are set to False with the given reason.
+func (carbonEstimator *CarbonEstimator) Error(conditionType, reason, msg string) {
 
 	carbonEstimator.Status.State = utils.ErrorStatus
-	carbonEstimator.Status.Consumption = utils.ErrorInt
-	carbonEstimator.Status.Emission = utils.ErrorInt
 	carbonEstimator.Status.ErrorMessage = msg
+
+	if conditionType != ConditionReady {
+		carbonEstimator.SetCondition(conditionType, metav1.ConditionFalse, reason, msg)
+	}
+	carbonEstimator.SetCondition(ConditionReady, metav1.ConditionFalse, reason, msg)
+}
+
+// ProviderChain returns the carbon intensity providers to try in order: spec.providers, else
+// spec.provider, else the default provider
+func (carbonEstimator *CarbonEstimator) ProviderChain() []ProviderSpec {
+	var chain []ProviderSpec
+	switch {
+	case len(carbonEstimator.Spec.Providers) > 0:
+		chain = append(chain, carbonEstimator.Spec.Providers...)
+	case carbonEstimator.Spec.Provider != nil:
+		chain = append(chain, *carbonEstimator.Spec.Provider)
+	default:
+		chain = append(chain, ProviderSpec{})
+	}
+
+	for i := range chain {
+		if chain[i].Name == "" {
+			chain[i].Name = utils.DefaultProvider
+		}
+	}
+	return chain
+}
+
+// ReconcileInterval returns the interval between two reconciles of the estimator, defaultInterval
+// when spec.interval is unset, and never less than the minimum interval
+func (carbonEstimator *CarbonEstimator) ReconcileInterval(defaultInterval time.Duration) time.Duration {
+	interval := defaultInterval
+	if carbonEstimator.Spec.Interval != nil {
+		interval = carbonEstimator.Spec.Interval.Duration
+	}
+	return max(interval, utils.MinReconcileInterval)
+}
+
+// IntensityMaxAge returns how long the last known good carbon intensity may be used
+func (carbonEstimator *CarbonEstimator) IntensityMaxAge() time.Duration {
+	if carbonEstimator.Spec.IntensityMaxAge == nil {
+		return utils.DefaultIntensityMaxAge
+	}
+	return carbonEstimator.Spec.IntensityMaxAge.Duration
+}
+
+// ForecastHorizon returns how far ahead the carbon intensity forecast is retrieved
+func (carbonEstimator *CarbonEstimator) ForecastHorizon() time.Duration {
+	if carbonEstimator.Spec.Forecast == nil || carbonEstimator.Spec.Forecast.Horizon == nil {
+		return utils.DefaultForecastHorizon
+	}
+	return carbonEstimator.Spec.Forecast.Horizon.Duration
+}
+
+// ForecastModel returns the built-in model forecasting when no provider publishes a forecast
+func (carbonEstimator *CarbonEstimator) ForecastModel() string {
+	if carbonEstimator.Spec.Forecast == nil || carbonEstimator.Spec.Forecast.Builtin == "" {
+		return utils.ForecastModelSeasonalNaive
+	}
+	return carbonEstimator.Spec.Forecast.Builtin
+}
+
+// ForecastHistoryDays returns how many days of intensity history the built-in forecast uses
+func (carbonEstimator *CarbonEstimator) ForecastHistoryDays() int {
+	if carbonEstimator.Spec.Forecast == nil || carbonEstimator.Spec.Forecast.HistoryDays == 0 {
+		return utils.DefaultForecastHistoryDays
+	}
+	return int(carbonEstimator.Spec.Forecast.HistoryDays)
+}
+
+// ResolvedZone returns the grid zone to query, falling back to the deprecated
+// TimeZone field. It is empty when neither is set, there is no default zone.
+func (carbonEstimator *CarbonEstimator) ResolvedZone() string {
+	if carbonEstimator.Spec.Zone != "" {
+		return carbonEstimator.Spec.Zone
+	}
+	if carbonEstimator.Spec.TimeZone != "" {
+		return carbonEstimator.Spec.TimeZone
+	}
+	return ""
+}
+
+// ZoneNodeLabel returns the node label holding the region of the nodes
+func (carbonEstimator *CarbonEstimator) ZoneNodeLabel() string {
+	if carbonEstimator.Spec.Zones == nil || carbonEstimator.Spec.Zones.NodeLabel == "" {
+		return utils.DefaultRegionLabel
+	}
+	return carbonEstimator.Spec.Zones.NodeLabel
+}
+
+// NodeZone returns the grid zone of a node with the given labels: the zone of its region in
+// spec.zones.byRegion, else the zone of the estimator
+func (carbonEstimator *CarbonEstimator) NodeZone(nodeLabels map[string]string) string {
+	if carbonEstimator.Spec.Zones != nil {
+		if region, ok := nodeLabels[carbonEstimator.ZoneNodeLabel()]; ok {
+			if zone, ok := carbonEstimator.Spec.Zones.ByRegion[region]; ok && zone != "" {
+				return zone
+			}
+		}
+	}
+	return carbonEstimator.ResolvedZone()
+}
+
+// SecretKey returns the namespaced name of the Secret referenced by ref, defaulting the namespace
+// to the one of the CarbonEstimator. ok is false when no Secret is referenced.
+func (carbonEstimator *CarbonEstimator) SecretKey(ref *SecretRef) (key types.NamespacedName, ok bool) {
+	if ref == nil || ref.Name == "" {
+		return types.NamespacedName{}, false
+	}
+
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = carbonEstimator.Namespace
+	}
+	return types.NamespacedName{Name: ref.Name, Namespace: namespace}, true
+}
+
+// ProviderSecretRef returns the Secret reference of the provider, defaulting to spec.secretRef
+func (carbonEstimator *CarbonEstimator) ProviderSecretRef(p ProviderSpec) *SecretRef {
+	if p.SecretRef != nil {
+		return p.SecretRef
+	}
+	return carbonEstimator.Spec.SecretRef
+}
+
+// SecretKeys returns the namespaced names of all Secrets referenced by the spec
+func (carbonEstimator *CarbonEstimator) SecretKeys() []types.NamespacedName {
+	var keys []types.NamespacedName
+	seen := map[types.NamespacedName]bool{}
+	for _, p := range carbonEstimator.ProviderChain() {
+		if key, ok := carbonEstimator.SecretKey(carbonEstimator.ProviderSecretRef(p)); ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ConfigMapKey returns the namespaced name of the ConfigMap referenced by ref,
+// defaulting the namespace to the one of the CarbonEstimator.
+func (carbonEstimator *CarbonEstimator) ConfigMapKey(ref *ConfigMapRef) types.NamespacedName {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = carbonEstimator.Namespace
+	}
+	return types.NamespacedName{Name: ref.Name, Namespace: namespace}
+}
+
+// ConfigMapKeys returns the namespaced names of all ConfigMaps referenced by the spec
+func (carbonEstimator *CarbonEstimator) ConfigMapKeys() []types.NamespacedName {
+	var keys []types.NamespacedName
+	if model := carbonEstimator.Spec.PowerModel; model != nil && model.CatalogRef != nil {
+		keys = append(keys, carbonEstimator.ConfigMapKey(model.CatalogRef))
+	}
+	if embodied := carbonEstimator.Spec.Embodied; embodied != nil && embodied.ConfigMapRef != nil {
+		keys = append(keys, carbonEstimator.ConfigMapKey(embodied.ConfigMapRef))
+	}
+	for _, p := range carbonEstimator.ProviderChain() {
+		if p.OptionsFrom != nil {
+			keys = append(keys, carbonEstimator.ConfigMapKey(p.OptionsFrom))
+		}
+	}
+	return keys
+}
+
+// DataKey returns the key of the token within the referenced Secret
+func (ref *SecretRef) DataKey() string {
+	if ref == nil || ref.Key == "" {
+		return utils.DefaultSecretKey
+	}
+	return ref.Key
+}
+
+// SetCondition adds or updates a status condition of the CarbonEstimator
+func (carbonEstimator *CarbonEstimator) SetCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&carbonEstimator.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: carbonEstimator.Generation,
+	})
+}
+
+// PreviousSample returns the power consumption measured by the last successful reconcile.
+// ok is false if there is none yet. watts is negative when the last reading is unknown.
+func (carbonEstimator *CarbonEstimator) PreviousSample() (watts float64, at time.Time, ok bool) {
+	if carbonEstimator.Status.LastSampleTime == nil {
+		return 0, time.Time{}, false
+	}
+
+	watts, err := strconv.ParseFloat(carbonEstimator.Status.Consumption, 64)
+	if err != nil {
+		watts = -1
+	}
+	return watts, carbonEstimator.Status.LastSampleTime.Time, true
+}
+
+// AccumulateEnergy adds the energy (kWh) and emissions (gCO2eq) of the last interval to the
+// cumulative totals and records when the interval ended
+func (carbonEstimator *CarbonEstimator) AccumulateEnergy(energyKWh, emissionGrams float64, sampleTime time.Time) {
+	energyTotal, _ := strconv.ParseFloat(carbonEstimator.Status.EnergyTotal, 64)
+	emissionTotal, _ := strconv.ParseFloat(carbonEstimator.Status.EmissionTotal, 64)
+
+	// totals are accumulated across reconciles, keep full precision
+	carbonEstimator.Status.EnergyTotal = strconv.FormatFloat(energyTotal+energyKWh, 'f', -1, 64)
+	carbonEstimator.Status.EmissionTotal = strconv.FormatFloat(emissionTotal+emissionGrams, 'f', -1, 64)
+	carbonEstimator.Status.LastSampleTime = &metav1.Time{Time: sampleTime}
+}
+
+// AttributionMode returns the attribution granularity selected by the spec
+func (carbonEstimator *CarbonEstimator) AttributionMode() string {
+	if carbonEstimator.Spec.Attribution == nil || carbonEstimator.Spec.Attribution.Mode == "" {
+		return utils.AttributionNone
+	}
+	return carbonEstimator.Spec.Attribution.Mode
+}
+
+// AttributionCPUWeight returns the fraction (0-1) of the power attributed by CPU usage
+func (carbonEstimator *CarbonEstimator) AttributionCPUWeight() float64 {
+	if carbonEstimator.Spec.Attribution == nil || carbonEstimator.Spec.Attribution.CPUWeight == nil {
+		return float64(utils.DefaultCPUWeight) / 100
+	}
+	return float64(*carbonEstimator.Spec.Attribution.CPUWeight) / 100
+}
+
+// AttributionTopN returns the number of attribution entries kept in status
+func (carbonEstimator *CarbonEstimator) AttributionTopN() int {
+	if carbonEstimator.Spec.Attribution == nil || carbonEstimator.Spec.Attribution.TopN <= 0 {
+		return utils.DefaultTopN
+	}
+	return int(carbonEstimator.Spec.Attribution.TopN)
+}
+
+// PowerModelType returns the power model selected by the spec
+func (carbonEstimator *CarbonEstimator) PowerModelType() string {
+	if carbonEstimator.Spec.PowerModel == nil || carbonEstimator.Spec.PowerModel.Type == "" {
+		return utils.PowerModelQuery
+	}
+	return carbonEstimator.Spec.PowerModel.Type
+}
+
+// PowerSource returns the source of the power readings, defaulting to the power model
+func (carbonEstimator *CarbonEstimator) PowerSource() string {
+	if carbonEstimator.Spec.PowerSource == "" {
+		return utils.PowerSourceModel
+	}
+	return carbonEstimator.Spec.PowerSource
+}
+
+// PowerModelNodeLabel returns the Prometheus label holding the node of the usage series of the power model
+func (carbonEstimator *CarbonEstimator) PowerModelNodeLabel() string {
+	if carbonEstimator.Spec.PowerModel != nil && carbonEstimator.Spec.PowerModel.NodeLabel != "" {
+		return carbonEstimator.Spec.PowerModel.NodeLabel
+	}
+	if carbonEstimator.PowerModelType() == utils.PowerModelIdleMax {
+		return utils.DefaultNodeExporterLabel
+	}
+	return utils.DefaultNodeLabel
 }
@@ -37,13 +37,299 @@ type CarbonEstimatorSpec struct {
 	// +optional
 	PowerMetricQuery string `json:"powerMetricQuery,omitempty"`
 
+	// PowerModel selects how the power consumption is obtained. Defaults to running powerMetricQuery.
+	// +optional
+	PowerModel *PowerModelSpec `json:"powerModel,omitempty"`
+
+	// PowerSource selects where power readings come from. model uses powerModel, kepler derives
+	// the power of the cluster, nodes, namespaces and pods from the energy counters of Kepler.
+	// +kubebuilder:validation:Enum=model;kepler
+	// +kubebuilder:default=model
+	// +optional
+	PowerSource string `json:"powerSource,omitempty"`
+
 	SecretRef *SecretRef `json:"secretRef,omitempty"`
-	TimeZone  string     `json:"timeZone,omitempty"`
+
+	// Interval between two reconciles of the estimator, at least 30s. Defaults to the
+	// --default-reconcile-interval of the manager. Every reconcile is delayed by up to 10% of the interval
+	// so that estimators do not query Prometheus and the providers in lockstep.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Deprecated: use Zone. Still honored as the grid zone when Zone is empty.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Zone is the electricity grid zone code passed to the carbon intensity provider (e.g. TW, DE, US-CAL-CISO,
+	// or a WattTime region such as CAISO_NORTH). Required, unless the deprecated TimeZone is set.
+	// +kubebuilder:validation:Pattern=`^[A-Z][A-Z0-9]+([-_][A-Z0-9]+)*$`
+	// +optional
+	Zone string `json:"zone,omitempty"`
+
+	// Provider selects the carbon intensity source. Defaults to Electricity Maps.
+	// +optional
+	Provider *ProviderSpec `json:"provider,omitempty"`
+
+	// Providers is an ordered fallback chain of carbon intensity sources, tried in turn until one
+	// succeeds. Takes precedence over provider.
+	// +kubebuilder:validation:MaxItems=5
+	// +optional
+	Providers []ProviderSpec `json:"providers,omitempty"`
+
+	// IntensityMaxAge is how long the last known good carbon intensity of the zone keeps being used
+	// when every provider fails. Zero disables the fallback.
+	// +kubebuilder:default="1h"
+	// +optional
+	IntensityMaxAge *metav1.Duration `json:"intensityMaxAge,omitempty"`
+
+	// Zones maps the nodes of a cluster spanning several regions to the grid zone of their region. The
+	// power of every node is multiplied by the carbon intensity of its own zone, which requires a power
+	// source breaking the consumption down per node (coefficients, idleMax or kepler).
+	// +optional
+	Zones *ZoneMappingSpec `json:"zones,omitempty"`
+
+	// Forecast enables the retrieval of the carbon intensity forecast of the zone, from the first
+	// provider of the chain publishing one
+	// +optional
+	Forecast *ForecastSpec `json:"forecast,omitempty"`
+
+	// Attribution splits the measured power across tenants of the cluster
+	// +optional
+	Attribution *AttributionSpec `json:"attribution,omitempty"`
+
+	// PUE is the Power Usage Effectiveness of the datacenter, applied to the IT power before
+	// emissions are computed. Defaults to 1, i.e. no facility overhead.
+	// +optional
+	PUE *PUESpec `json:"pue,omitempty"`
+
+	// Overhead adds facility overheads not covered by the PUE, relative to the IT power
+	// +optional
+	Overhead *OverheadSpec `json:"overhead,omitempty"`
+
+	// Embodied enables the embodied (scope 3) emissions of the nodes, amortized over their lifespan
+	// +optional
+	Embodied *EmbodiedSpec `json:"embodied,omitempty"`
+}
+
+// ForecastSpec configures the carbon intensity forecast.
+type ForecastSpec struct {
+	// Horizon is how far ahead the forecast is retrieved, providers publish up to 24-72h
+	// +kubebuilder:default="24h"
+	// +optional
+	Horizon *metav1.Duration `json:"horizon,omitempty"`
+
+	// Builtin is the model forecasting from the intensity history collected by the operator when no
+	// provider of the chain publishes a forecast. seasonalNaive averages the same hour of the previous
+	// days, holtWinters applies exponential smoothing with daily seasonality and needs two days of history.
+	// +kubebuilder:validation:Enum=seasonalNaive;holtWinters;none
+	// +kubebuilder:default=seasonalNaive
+	// +optional
+	Builtin string `json:"builtin,omitempty"`
+
+	// HistoryDays is how many days of intensity history the built-in model uses
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=28
+	// +kubebuilder:default=7
+	// +optional
+	HistoryDays int32 `json:"historyDays,omitempty"`
+}
+
+// ZoneMappingSpec maps nodes to grid zones by the region they run in.
+type ZoneMappingSpec struct {
+	// NodeLabel is the node label holding the region of the node
+	// +kubebuilder:default="topology.kubernetes.io/region"
+	// +optional
+	NodeLabel string `json:"nodeLabel,omitempty"`
+
+	// ByRegion maps regions to grid zone codes, e.g. eu-central-1: DE. Nodes of other regions, or
+	// without the label, belong to spec.zone.
+	ByRegion map[string]string `json:"byRegion"`
+}
+
+// EmbodiedSpec configures the manufacturing emissions of the nodes. They are taken from the
+// built-in instance type catalog, or estimated from the vCPUs of nodes of unknown instance types.
+type EmbodiedSpec struct {
+	// ConfigMapRef points at a ConfigMap in the instance type catalog format providing embodied
+	// emissions (embodiedKgCO2e, lifespanYears) per instance type. Defaults the key to embodied.yaml.
+	// +optional
+	ConfigMapRef *ConfigMapRef `json:"configMapRef,omitempty"`
+}
+
+// PUESpec is a static PUE, optionally refined per node label (e.g. per datacenter).
+type PUESpec struct {
+	// Value is the PUE of nodes not matched by byNodeLabel
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +kubebuilder:default="1"
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// NodeLabel is the node label whose value selects an entry of byNodeLabel
+	// +optional
+	NodeLabel string `json:"nodeLabel,omitempty"`
+
+	// ByNodeLabel maps values of nodeLabel to the PUE of the nodes carrying them
+	// +optional
+	ByNodeLabel map[string]string `json:"byNodeLabel,omitempty"`
+}
+
+// OverheadSpec holds facility overhead factors, e.g. 0.1 adds 10% of the IT power.
+type OverheadSpec struct {
+	// Cooling overhead factor
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	Cooling string `json:"cooling,omitempty"`
+
+	// Networking overhead factor
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	Networking string `json:"networking,omitempty"`
+}
+
+// AttributionSpec configures how the measured power is split across namespaces and workloads.
+type AttributionSpec struct {
+	// Mode selects the attribution granularity. None disables attribution, Workload attributes
+	// power to namespaces and to the workloads (Deployment, StatefulSet, CronJob, ...) owning the pods.
+	// +kubebuilder:validation:Enum=None;Namespace;Workload
+	// +kubebuilder:default=None
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// CPUWeight is the percentage of the power split by CPU usage, the remainder is split by memory usage
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=50
+	// +optional
+	CPUWeight *int32 `json:"cpuWeight,omitempty"`
+
+	// TopN limits the number of entries written to the status breakdown
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	// +optional
+	TopN int32 `json:"topN,omitempty"`
+}
+
+// PowerModelSpec selects how the power consumption of the cluster is obtained.
+type PowerModelSpec struct {
+	// Type of the power model. query runs powerMetricQuery against Prometheus, coefficients
+	// estimates the power of every node from its CPU and memory usage, idleMax interpolates the
+	// power of every node between its idle and maximum power draw by CPU utilization.
+	// +kubebuilder:validation:Enum=query;coefficients;idleMax
+	// +kubebuilder:default=query
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Coefficients per node class, used by the coefficients model. Every node uses the first
+	// class whose node selector matches its labels.
+	// +optional
+	Coefficients []NodeClassCoefficients `json:"coefficients,omitempty"`
+
+	// Profiles of the node classes, used by the idleMax model. Every node uses the first
+	// profile whose node selector matches its labels, else the catalog profile of its
+	// node.kubernetes.io/instance-type, else a default profile scaled by its vCPUs.
+	// +optional
+	Profiles []PowerProfile `json:"profiles,omitempty"`
+
+	// CatalogRef points at a ConfigMap whose instance types are added to, or replace those of,
+	// the built-in instance type catalog of the idleMax model. Defaults the key to catalog.yaml.
+	// +optional
+	CatalogRef *ConfigMapRef `json:"catalogRef,omitempty"`
+
+	// NodeLabel is the Prometheus label holding the node name (or address) of the usage series.
+	// Defaults to node for the cAdvisor series of the coefficients model and to instance for
+	// the node-exporter series of the idleMax model.
+	// +optional
+	NodeLabel string `json:"nodeLabel,omitempty"`
+}
+
+// PowerProfile is the power characteristics of a class of nodes, e.g. an instance type or CPU model.
+type PowerProfile struct {
+	// Name of the profile
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// NodeSelector matches the labels of the nodes of the profile (e.g. node.kubernetes.io/instance-type).
+	// An empty selector matches every node.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// IdleWatts is the power draw of the CPUs of an idle node in W
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	IdleWatts string `json:"idleWatts"`
+
+	// MaxWatts is the power draw of the CPUs of a fully utilized node in W
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	MaxWatts string `json:"maxWatts"`
+
+	// MemoryWattsPerGB is the power draw per used GB of memory in W
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	MemoryWattsPerGB string `json:"memoryWattsPerGB,omitempty"`
 }
 
+// NodeClassCoefficients is the power draw of a class of nodes per unit of used resource.
+type NodeClassCoefficients struct {
+	// Name of the node class
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// NodeSelector matches the labels of the nodes of the class. An empty selector matches every node.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// CPU is the power draw per used core in W
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	CPU string `json:"cpu"`
+
+	// Memory is the power draw per used GB of memory in W
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	Memory string `json:"memory"`
+}
+
+// ProviderSpec selects a carbon intensity provider and configures it.
+type ProviderSpec struct {
+	// Name of a registered carbon intensity provider (e.g. electricitymaps, watttime, carbonintensityuk, static)
+	// +kubebuilder:default=electricitymaps
+	Name string `json:"name"`
+
+	// Options are passed as-is to the provider. Supported keys depend on the provider.
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+
+	// OptionsFrom points at a ConfigMap whose data is passed to the provider as options, e.g. the
+	// profile or time series of the static provider. Options set in the spec take precedence.
+	// The key is ignored, every key of the ConfigMap is an option.
+	// +optional
+	OptionsFrom *ConfigMapRef `json:"optionsFrom,omitempty"`
+
+	// SecretRef points at the Secret holding the token of this provider. Defaults to spec.secretRef.
+	// +optional
+	SecretRef *SecretRef `json:"secretRef,omitempty"`
+}
+
+// SecretRef points at the Secret holding the carbon intensity provider API token.
 type SecretRef struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
+	Name string `json:"name"`
+
+	// Namespace of the Secret. Defaults to the namespace of the CarbonEstimator.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key within the Secret data holding the token. Defaults to "token".
+	// +kubebuilder:default=token
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// ConfigMapRef points at a key of a ConfigMap.
+type ConfigMapRef struct {
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap. Defaults to the namespace of the CarbonEstimator.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key within the ConfigMap data. The default depends on the referencing field.
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
 // CarbonEstimatorStatus defines the observed state of CarbonEstimator.
@@ -53,13 +339,180 @@ type CarbonEstimatorStatus struct {
 
 	State           string `json:"state,omitempty"`
 	CarbonIntensity string `json:"carbonIntensity,omitempty"` // 碳強度（從 API 拿值，之後再配合comsumption算出emission）
-	Consumption     string `json:"consumption,omitempty"`
-	Emission        string `json:"emission,omitempty"`
+	Zone            string `json:"zone,omitempty"`            // grid zone the carbon intensity was fetched for
+	Consumption     string `json:"consumption,omitempty"`     // IT power consumption in W
+	Emission        string `json:"emission,omitempty"`        // emission rate in gCO2eq/h
 	ErrorMessage    string `json:"errorMessage,omitempty"`
+
+	// SignalType of the carbon intensity, average or marginal (MOER)
+	// +optional
+	SignalType string `json:"signalType,omitempty"`
+	// IntensityIndex is the relative level of the carbon intensity reported by the provider, if any
+	// +optional
+	IntensityIndex string `json:"intensityIndex,omitempty"`
+	// IntensityForecast and IntensityActual are the forecast and measured carbon intensity in gCO2eq/kWh,
+	// for providers reporting them separately
+	// +optional
+	IntensityForecast string `json:"intensityForecast,omitempty"`
+	// +optional
+	IntensityActual string `json:"intensityActual,omitempty"`
+	// IntensitySource is the provider the carbon intensity comes from, or lastKnownGood when every
+	// provider failed and the last known good value is used
+	// +optional
+	IntensitySource string `json:"intensitySource,omitempty"`
+	// IntensityTime is when the carbon intensity in use was fetched
+	// +optional
+	IntensityTime *metav1.Time `json:"intensityTime,omitempty"`
+	// IntensityAge is the age of the carbon intensity in use, e.g. 15m0s
+	// +optional
+	IntensityAge string `json:"intensityAge,omitempty"`
+	// Forecast is the hourly carbon intensity forecast of the zone, set when spec.forecast is
+	// +optional
+	Forecast *CarbonIntensityForecast `json:"forecast,omitempty"`
+
+	// FacilityConsumption is the IT power consumption including the datacenter overhead (PUE,
+	// cooling, networking) in W. Emissions are computed from it.
+	// +optional
+	FacilityConsumption string `json:"facilityConsumption,omitempty"`
+
+	// EmbodiedEmission is the embodied emission rate of the nodes amortized over their lifespan, in gCO2eq/h
+	// +optional
+	EmbodiedEmission string `json:"embodiedEmission,omitempty"`
+
+	// EnergyTotal is the cumulative facility energy consumed since the estimator was created, in kWh
+	// +optional
+	EnergyTotal string `json:"energyTotal,omitempty"`
+	// EmissionTotal is the cumulative emissions since the estimator was created, in gCO2eq
+	// +optional
+	EmissionTotal string `json:"emissionTotal,omitempty"`
+	// LastSampleTime is when the power consumption was last measured successfully
+	// +optional
+	LastSampleTime *metav1.Time `json:"lastSampleTime,omitempty"`
+
+	// Zones is the breakdown of the facility power and emission rate per grid zone, set when spec.zones is
+	// +optional
+	Zones []ZoneEmission `json:"zones,omitempty"`
+
+	// Namespaces is the breakdown of the top namespaces by attributed power
+	// +optional
+	Namespaces []NamespaceEmission `json:"namespaces,omitempty"`
+
+	// Workloads is the breakdown of the top workloads by attributed power
+	// +optional
+	Workloads []WorkloadEmission `json:"workloads,omitempty"`
+
+	// ConsecutiveFailures is the number of reconciles that failed in a row since the last successful one
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+	// NextRetryTime is when a failed estimator is reconciled again. Transient errors back off exponentially
+	// with the consecutive failures, permanent errors (e.g. rejected credentials or an invalid query) are
+	// retried once the estimator or a referenced object changes, or after the maximum backoff.
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was last computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastUpdateTime is when the status was last written by the controller
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// Conditions describe the latest observations of the estimator's dependencies. Ready is True once
+	// the power and emissions are up to date.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CarbonIntensityForecast is a carbon intensity forecast of the grid zone.
+type CarbonIntensityForecast struct {
+	// Source is the provider that published the forecast, or builtin when it is estimated from
+	// the intensity history
+	Source string `json:"source"`
+	// Model is the built-in model of builtin forecasts
+	// +optional
+	Model string `json:"model,omitempty"`
+	// UpdateTime is when the forecast was retrieved
+	UpdateTime metav1.Time `json:"updateTime"`
+	// MeanAbsolutePercentageError of the forecasts of the last day against the actual intensity, as a
+	// ratio. Each hour is evaluated against the latest forecast issued before it started.
+	// +optional
+	MeanAbsolutePercentageError string `json:"meanAbsolutePercentageError,omitempty"`
+	// Points are the forecast hours, sorted by time
+	// +optional
+	Points []ForecastPoint `json:"points,omitempty"`
+}
+
+// ForecastPoint is the forecast carbon intensity of an hour.
+type ForecastPoint struct {
+	// Time is the start of the hour
+	Time metav1.Time `json:"time"`
+	// Value is the forecast carbon intensity in gCO2eq/kWh
+	Value string `json:"value"`
+}
+
+// ZoneEmission is the power and emission of the nodes of the estimator in a grid zone.
+type ZoneEmission struct {
+	Zone            string `json:"zone"`
+	Nodes           int32  `json:"nodes"`           // number of nodes in the zone
+	CarbonIntensity string `json:"carbonIntensity"` // carbon intensity of the zone in gCO2eq/kWh
+	Power           string `json:"power"`           // facility power consumption in W
+	Emission        string `json:"emission"`        // emission rate in gCO2eq/h
+	// IntensitySource is the provider the carbon intensity of the zone comes from, or lastKnownGood
+	// +optional
+	IntensitySource string `json:"intensitySource,omitempty"`
+	// IntensityTime is when the carbon intensity of the zone was fetched
+	// +optional
+	IntensityTime *metav1.Time `json:"intensityTime,omitempty"`
+}
+
+// NamespaceEmission is the power and emission attributed to a namespace.
+type NamespaceEmission struct {
+	Namespace string `json:"namespace"`
+	Share     string `json:"share"`    // fraction of the estimator's power, between 0 and 1
+	Power     string `json:"power"`    // power consumption in W
+	Emission  string `json:"emission"` // emission rate in gCO2eq/h
+	// EmbodiedEmission is the share of the embodied emission rate in gCO2eq/h, set when spec.embodied is
+	// +optional
+	EmbodiedEmission string `json:"embodiedEmission,omitempty"`
+}
+
+// WorkloadEmission is the power and emission attributed to a workload.
+type WorkloadEmission struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Share     string `json:"share"`    // fraction of the estimator's power, between 0 and 1
+	Power     string `json:"power"`    // power consumption in W
+	Emission  string `json:"emission"` // emission rate in gCO2eq/h
+	// EmbodiedEmission is the share of the embodied emission rate in gCO2eq/h, set when spec.embodied is
+	// +optional
+	EmbodiedEmission string `json:"embodiedEmission,omitempty"`
 }
 
+const (
+	// ConditionReady reports whether the last reconcile measured the power and computed the emissions
+	ConditionReady = "Ready"
+	// ConditionPrometheusReachable reports whether the Prometheus of spec.prometheusURL is healthy
+	ConditionPrometheusReachable = "PrometheusReachable"
+	// ConditionPowerMeasured reports whether the power consumption could be measured
+	ConditionPowerMeasured = "PowerMeasured"
+	// ConditionIntensityAvailable reports whether a carbon intensity, fetched or last known good, is available
+	ConditionIntensityAvailable = "IntensityAvailable"
+	// ConditionSecretResolved reports whether the token referenced by spec.secretRef could be read
+	ConditionSecretResolved = "SecretResolved"
+	// ConditionAttributed reports whether the power could be attributed to namespaces and workloads
+	ConditionAttributed = "Attributed"
+	// ConditionInstanceTypesResolved reports whether the idleMax model found a power profile for every node
+	ConditionInstanceTypesResolved = "InstanceTypesResolved"
+	// ConditionForecastAvailable reports whether a carbon intensity forecast could be retrieved
+	ConditionForecastAvailable = "ForecastAvailable"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // CarbonEstimator is the Schema for the carbonestimators API.
 type CarbonEstimator struct {
@@ -0,0 +1,240 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"sustain_kube/internal/utils"
+)
+
+// log is for logging in this package.
+var carbonestimatorlog = logf.Log.WithName("carbonestimator-resource")
+
+// zonePattern matches grid zone codes, as enforced on spec.zone by the CRD schema
+var zonePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]+([-_][A-Z0-9]+)*$`)
+
+// maxForecastHorizon is the furthest providers publish forecasts for
+const maxForecastHorizon = 72 * time.Hour
+
+// SetupWebhookWithManager registers the webhooks of CarbonEstimator, including the conversion webhook
+// serving the other API versions. Estimators querying zones outside allowedZones are rejected, an empty
+// list allows every zone.
+func (r *CarbonEstimator) SetupWebhookWithManager(mgr ctrl.Manager, allowedZones []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&CarbonEstimatorCustomDefaulter{}).
+		WithValidator(&CarbonEstimatorCustomValidator{AllowedZones: allowedZones}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-sustain-kube-com-v1alpha1-carbonestimator,mutating=true,failurePolicy=fail,sideEffects=None,groups=sustain-kube.com,resources=carbonestimators,verbs=create;update,versions=v1alpha1,name=mcarbonestimator-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// CarbonEstimatorCustomDefaulter sets the default power query and intervals of CarbonEstimators.
+//
+// +kubebuilder:object:generate=false
+type CarbonEstimatorCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &CarbonEstimatorCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type CarbonEstimator.
+func (d *CarbonEstimatorCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	carbonEstimator, ok := obj.(*CarbonEstimator)
+	if !ok {
+		return fmt.Errorf("expected a CarbonEstimator object but got %T", obj)
+	}
+	carbonestimatorlog.Info("Defaulting for CarbonEstimator", "name", carbonEstimator.GetName())
+
+	spec := &carbonEstimator.Spec
+	if spec.PowerMetricQuery == "" &&
+		carbonEstimator.PowerSource() == utils.PowerSourceModel &&
+		carbonEstimator.PowerModelType() == utils.PowerModelQuery {
+		spec.PowerMetricQuery = utils.DefaultPowerQuery
+	}
+	if spec.IntensityMaxAge == nil {
+		spec.IntensityMaxAge = &metav1.Duration{Duration: utils.DefaultIntensityMaxAge}
+	}
+	if spec.Forecast != nil {
+		if spec.Forecast.Horizon == nil {
+			spec.Forecast.Horizon = &metav1.Duration{Duration: utils.DefaultForecastHorizon}
+		}
+		if spec.Forecast.Builtin == "" {
+			spec.Forecast.Builtin = utils.ForecastModelSeasonalNaive
+		}
+		if spec.Forecast.HistoryDays == 0 {
+			spec.Forecast.HistoryDays = utils.DefaultForecastHistoryDays
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-sustain-kube-com-v1alpha1-carbonestimator,mutating=false,failurePolicy=fail,sideEffects=None,groups=sustain-kube.com,resources=carbonestimators,verbs=create;update,versions=v1alpha1,name=vcarbonestimator-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// CarbonEstimatorCustomValidator rejects CarbonEstimators the controller could not reconcile.
+//
+// +kubebuilder:object:generate=false
+type CarbonEstimatorCustomValidator struct {
+	// AllowedZones restricts the grid zones estimators may query. Empty allows any zone.
+	AllowedZones []string
+}
+
+var _ webhook.CustomValidator = &CarbonEstimatorCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type CarbonEstimator.
+func (v *CarbonEstimatorCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	carbonEstimator, ok := obj.(*CarbonEstimator)
+	if !ok {
+		return nil, fmt.Errorf("expected a CarbonEstimator object but got %T", obj)
+	}
+	carbonestimatorlog.Info("Validation for CarbonEstimator upon creation", "name", carbonEstimator.GetName())
+
+	return v.validate(carbonEstimator)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type CarbonEstimator.
+func (v *CarbonEstimatorCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	carbonEstimator, ok := newObj.(*CarbonEstimator)
+	if !ok {
+		return nil, fmt.Errorf("expected a CarbonEstimator object for the newObj but got %T", newObj)
+	}
+	carbonestimatorlog.Info("Validation for CarbonEstimator upon update", "name", carbonEstimator.GetName())
+
+	return v.validate(carbonEstimator)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type CarbonEstimator.
+func (v *CarbonEstimatorCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the spec of the estimator, returning every invalid field at once.
+func (v *CarbonEstimatorCustomValidator) validate(carbonEstimator *CarbonEstimator) (admission.Warnings, error) {
+	var warnings admission.Warnings
+	var errs field.ErrorList
+	spec := carbonEstimator.Spec
+	specPath := field.NewPath("spec")
+
+	if err := validatePrometheusURL(spec.PrometheusURL); err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("prometheusURL"), spec.PrometheusURL, err.Error()))
+	}
+
+	if spec.WarningLevel > spec.CriticalLevel {
+		errs = append(errs, field.Invalid(specPath.Child("levelWarning"), spec.WarningLevel,
+			fmt.Sprintf("must not be above levelCritical (%d)", spec.CriticalLevel)))
+	}
+
+	if spec.PowerMetricQuery != "" {
+		if err := validatePowerQuery(spec.PowerMetricQuery); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("powerMetricQuery"), spec.PowerMetricQuery, err.Error()))
+		}
+	}
+
+	if spec.TimeZone != "" {
+		warnings = append(warnings, "spec.timeZone is deprecated, use spec.zone")
+	}
+	zonePath := specPath.Child("zone")
+	if spec.Zone == "" && spec.TimeZone != "" {
+		zonePath = specPath.Child("timeZone")
+	}
+	if zone := carbonEstimator.ResolvedZone(); zone == "" {
+		errs = append(errs, field.Required(zonePath, "must be set to a grid zone code, e.g. TW, DE, US-CAL-CISO or CAISO_NORTH"))
+	} else {
+		errs = append(errs, v.validateZone(zonePath, zone)...)
+	}
+
+	if zones := spec.Zones; zones != nil {
+		zonesPath := specPath.Child("zones")
+		if carbonEstimator.PowerSource() == utils.PowerSourceModel &&
+			carbonEstimator.PowerModelType() == utils.PowerModelQuery {
+			errs = append(errs, field.Invalid(zonesPath, "", "requires the power of every node, "+
+				"use the coefficients or idleMax power model or the kepler power source"))
+		}
+		regions := make([]string, 0, len(zones.ByRegion))
+		for region := range zones.ByRegion {
+			regions = append(regions, region)
+		}
+		sort.Strings(regions)
+		for _, region := range regions {
+			errs = append(errs, v.validateZone(zonesPath.Child("byRegion").Key(region), zones.ByRegion[region])...)
+		}
+	}
+
+	if spec.Interval != nil && spec.Interval.Duration < utils.MinReconcileInterval {
+		errs = append(errs, field.Invalid(specPath.Child("interval"), spec.Interval.Duration.String(),
+			fmt.Sprintf("must be at least %s", utils.MinReconcileInterval)))
+	}
+	if spec.IntensityMaxAge != nil && spec.IntensityMaxAge.Duration < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("intensityMaxAge"), spec.IntensityMaxAge.Duration.String(),
+			"must not be negative"))
+	}
+	if spec.Forecast != nil && spec.Forecast.Horizon != nil {
+		if horizon := spec.Forecast.Horizon.Duration; horizon < time.Hour || horizon > maxForecastHorizon {
+			errs = append(errs, field.Invalid(specPath.Child("forecast", "horizon"), horizon.String(),
+				fmt.Sprintf("must be between 1h and %s", maxForecastHorizon)))
+		}
+	}
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(GroupVersion.WithKind("CarbonEstimator").GroupKind(),
+		carbonEstimator.Name, errs)
+}
+
+// validateZone checks the format of a grid zone code and that it is allowed.
+func (v *CarbonEstimatorCustomValidator) validateZone(path *field.Path, zone string) field.ErrorList {
+	if !zonePattern.MatchString(zone) {
+		return field.ErrorList{field.Invalid(path, zone, "must be a grid zone code, e.g. TW, DE, US-CAL-CISO or CAISO_NORTH")}
+	}
+	if len(v.AllowedZones) == 0 {
+		return nil
+	}
+	for _, allowed := range v.AllowedZones {
+		if strings.EqualFold(zone, allowed) {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(path, zone, v.AllowedZones)}
+}
+
+// validatePrometheusURL checks that the Prometheus URL is an absolute http(s) URL.
+func validatePrometheusURL(prometheusURL string) error {
+	u, err := url.Parse(prometheusURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("host is missing")
+	}
+	return nil
+}
+
+// validatePowerQuery parses the power query, which is also run as a range query for the energy and must
+// therefore return an instant vector.
+func validatePowerQuery(query string) error {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return err
+	}
+	if expr.Type() != parser.ValueTypeVector {
+		return fmt.Errorf("must return an instant vector, got %s", expr.Type())
+	}
+	return nil
+}
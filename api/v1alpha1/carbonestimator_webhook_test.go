@@ -0,0 +1,127 @@
+//go:build unit
+// +build unit
+
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sustain_kube/internal/utils"
+)
+
+func validEstimator() *CarbonEstimator {
+	return &CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec: CarbonEstimatorSpec{
+			PrometheusURL:    "http://prometheus-k8s.monitoring.svc.cluster.local:9090",
+			WarningLevel:     5,
+			CriticalLevel:    10,
+			PowerMetricQuery: "sum(node_power_watts)",
+			Zone:             "TW",
+		},
+	}
+}
+
+func TestDefault_QueryAndIntervals(t *testing.T) {
+	ce := validEstimator()
+	ce.Spec.PowerMetricQuery = ""
+	ce.Spec.Forecast = &ForecastSpec{}
+
+	if err := (&CarbonEstimatorCustomDefaulter{}).Default(context.Background(), ce); err != nil {
+		t.Fatalf("Default failed: %v", err)
+	}
+	if ce.Spec.PowerMetricQuery != utils.DefaultPowerQuery {
+		t.Fatalf("unexpected query: %q", ce.Spec.PowerMetricQuery)
+	}
+	if ce.Spec.IntensityMaxAge.Duration != time.Hour || ce.Spec.Forecast.Horizon.Duration != 24*time.Hour {
+		t.Fatalf("unexpected intervals: %v %v", ce.Spec.IntensityMaxAge, ce.Spec.Forecast.Horizon)
+	}
+	if ce.Spec.Forecast.Builtin != utils.ForecastModelSeasonalNaive || ce.Spec.Forecast.HistoryDays != 7 {
+		t.Fatalf("unexpected forecast defaults: %+v", ce.Spec.Forecast)
+	}
+
+	// the query is only defaulted for the query power model
+	ce = validEstimator()
+	ce.Spec.PowerMetricQuery = ""
+	ce.Spec.PowerSource = utils.PowerSourceKepler
+	_ = (&CarbonEstimatorCustomDefaulter{}).Default(context.Background(), ce)
+	if ce.Spec.PowerMetricQuery != "" {
+		t.Fatalf("unexpected query for kepler: %q", ce.Spec.PowerMetricQuery)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*CarbonEstimator)
+		field  string
+	}{
+		{"valid", func(*CarbonEstimator) {}, ""},
+		{"relative URL", func(ce *CarbonEstimator) { ce.Spec.PrometheusURL = "prometheus:9090" }, "spec.prometheusURL"},
+		{"unsupported scheme", func(ce *CarbonEstimator) { ce.Spec.PrometheusURL = "ftp://prometheus" }, "spec.prometheusURL"},
+		{"equal thresholds", func(ce *CarbonEstimator) { ce.Spec.WarningLevel = 10 }, ""},
+		{"threshold order", func(ce *CarbonEstimator) { ce.Spec.WarningLevel = 11 }, "spec.levelWarning"},
+		{"invalid PromQL", func(ce *CarbonEstimator) { ce.Spec.PowerMetricQuery = "sum(node_power_watts" }, "spec.powerMetricQuery"},
+		{"range vector", func(ce *CarbonEstimator) { ce.Spec.PowerMetricQuery = "node_power_watts[5m]" }, "spec.powerMetricQuery"},
+		{"zone missing", func(ce *CarbonEstimator) { ce.Spec.Zone = "" }, "spec.zone"},
+		{"zone code", func(ce *CarbonEstimator) { ce.Spec.Zone = ""; ce.Spec.TimeZone = "Asia/Taipei" }, "spec.timeZone"},
+		{"zone not allowed", func(ce *CarbonEstimator) { ce.Spec.Zone = "FR" }, "spec.zone"},
+		{"region zone code", func(ce *CarbonEstimator) {
+			ce.Spec.PowerModel = &PowerModelSpec{Type: utils.PowerModelCoefficients}
+			ce.Spec.Zones = &ZoneMappingSpec{ByRegion: map[string]string{"eu-central-1": "germany"}}
+		}, "spec.zones.byRegion[eu-central-1]"},
+		{"zones without node power", func(ce *CarbonEstimator) {
+			ce.Spec.Zones = &ZoneMappingSpec{ByRegion: map[string]string{"eu-central-1": "DE"}}
+		}, "spec.zones"},
+		{"interval", func(ce *CarbonEstimator) {
+			ce.Spec.Interval = &metav1.Duration{Duration: 10 * time.Second}
+		}, "spec.interval"},
+		{"forecast horizon", func(ce *CarbonEstimator) {
+			ce.Spec.Forecast = &ForecastSpec{Horizon: &metav1.Duration{Duration: 96 * time.Hour}}
+		}, "spec.forecast.horizon"},
+	}
+
+	validator := &CarbonEstimatorCustomValidator{AllowedZones: []string{"TW", "DE"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce := validEstimator()
+			tt.mutate(ce)
+
+			_, err := validator.ValidateCreate(context.Background(), ce)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.field+":") {
+				t.Fatalf("expected an error on %s, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestValidate_WattTimeRegion(t *testing.T) {
+	ce := validEstimator()
+	ce.Spec.Zone = "CAISO_NORTH"
+
+	if _, err := (&CarbonEstimatorCustomValidator{}).ValidateCreate(context.Background(), ce); err != nil {
+		t.Fatalf("unexpected error for a WattTime region: %v", err)
+	}
+}
+
+func TestValidate_DeprecatedTimeZoneWarning(t *testing.T) {
+	ce := validEstimator()
+	ce.Spec.Zone = ""
+	ce.Spec.TimeZone = "TW"
+
+	warnings, err := (&CarbonEstimatorCustomValidator{}).ValidateUpdate(context.Background(), ce, ce)
+	if err != nil || len(warnings) != 1 {
+		t.Fatalf("expected a deprecation warning only: %v %v", warnings, err)
+	}
+}
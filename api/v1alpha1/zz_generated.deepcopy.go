@@ -21,16 +21,37 @@ limitations under the License.
 package v1alpha1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AttributionSpec) DeepCopyInto(out *AttributionSpec) {
+	*out = *in
+	if in.CPUWeight != nil {
+		in, out := &in.CPUWeight, &out.CPUWeight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttributionSpec.
+func (in *AttributionSpec) DeepCopy() *AttributionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AttributionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CarbonEstimator) DeepCopyInto(out *CarbonEstimator) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonEstimator.
@@ -86,11 +107,68 @@ func (in *CarbonEstimatorList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CarbonEstimatorSpec) DeepCopyInto(out *CarbonEstimatorSpec) {
 	*out = *in
+	if in.PowerModel != nil {
+		in, out := &in.PowerModel, &out.PowerModel
+		*out = new(PowerModelSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.SecretRef != nil {
 		in, out := &in.SecretRef, &out.SecretRef
 		*out = new(SecretRef)
 		**out = **in
 	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Provider != nil {
+		in, out := &in.Provider, &out.Provider
+		*out = new(ProviderSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Providers != nil {
+		in, out := &in.Providers, &out.Providers
+		*out = make([]ProviderSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IntensityMaxAge != nil {
+		in, out := &in.IntensityMaxAge, &out.IntensityMaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = new(ZoneMappingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Attribution != nil {
+		in, out := &in.Attribution, &out.Attribution
+		*out = new(AttributionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PUE != nil {
+		in, out := &in.PUE, &out.PUE
+		*out = new(PUESpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Overhead != nil {
+		in, out := &in.Overhead, &out.Overhead
+		*out = new(OverheadSpec)
+		**out = **in
+	}
+	if in.Embodied != nil {
+		in, out := &in.Embodied, &out.Embodied
+		*out = new(EmbodiedSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonEstimatorSpec.
@@ -106,6 +184,51 @@ func (in *CarbonEstimatorSpec) DeepCopy() *CarbonEstimatorSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CarbonEstimatorStatus) DeepCopyInto(out *CarbonEstimatorStatus) {
 	*out = *in
+	if in.IntensityTime != nil {
+		in, out := &in.IntensityTime, &out.IntensityTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(CarbonIntensityForecast)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastSampleTime != nil {
+		in, out := &in.LastSampleTime, &out.LastSampleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]ZoneEmission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceEmission, len(*in))
+		copy(*out, *in)
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make([]WorkloadEmission, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonEstimatorStatus.
@@ -118,6 +241,262 @@ func (in *CarbonEstimatorStatus) DeepCopy() *CarbonEstimatorStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CarbonIntensityForecast) DeepCopyInto(out *CarbonIntensityForecast) {
+	*out = *in
+	in.UpdateTime.DeepCopyInto(&out.UpdateTime)
+	if in.Points != nil {
+		in, out := &in.Points, &out.Points
+		*out = make([]ForecastPoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonIntensityForecast.
+func (in *CarbonIntensityForecast) DeepCopy() *CarbonIntensityForecast {
+	if in == nil {
+		return nil
+	}
+	out := new(CarbonIntensityForecast)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapRef) DeepCopyInto(out *ConfigMapRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapRef.
+func (in *ConfigMapRef) DeepCopy() *ConfigMapRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmbodiedSpec) DeepCopyInto(out *EmbodiedSpec) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ConfigMapRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmbodiedSpec.
+func (in *EmbodiedSpec) DeepCopy() *EmbodiedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EmbodiedSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForecastPoint) DeepCopyInto(out *ForecastPoint) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForecastPoint.
+func (in *ForecastPoint) DeepCopy() *ForecastPoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ForecastPoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForecastSpec) DeepCopyInto(out *ForecastSpec) {
+	*out = *in
+	if in.Horizon != nil {
+		in, out := &in.Horizon, &out.Horizon
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForecastSpec.
+func (in *ForecastSpec) DeepCopy() *ForecastSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ForecastSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceEmission) DeepCopyInto(out *NamespaceEmission) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceEmission.
+func (in *NamespaceEmission) DeepCopy() *NamespaceEmission {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceEmission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeClassCoefficients) DeepCopyInto(out *NodeClassCoefficients) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeClassCoefficients.
+func (in *NodeClassCoefficients) DeepCopy() *NodeClassCoefficients {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeClassCoefficients)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverheadSpec) DeepCopyInto(out *OverheadSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverheadSpec.
+func (in *OverheadSpec) DeepCopy() *OverheadSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OverheadSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PUESpec) DeepCopyInto(out *PUESpec) {
+	*out = *in
+	if in.ByNodeLabel != nil {
+		in, out := &in.ByNodeLabel, &out.ByNodeLabel
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PUESpec.
+func (in *PUESpec) DeepCopy() *PUESpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PUESpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerModelSpec) DeepCopyInto(out *PowerModelSpec) {
+	*out = *in
+	if in.Coefficients != nil {
+		in, out := &in.Coefficients, &out.Coefficients
+		*out = make([]NodeClassCoefficients, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]PowerProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CatalogRef != nil {
+		in, out := &in.CatalogRef, &out.CatalogRef
+		*out = new(ConfigMapRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerModelSpec.
+func (in *PowerModelSpec) DeepCopy() *PowerModelSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerModelSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerProfile) DeepCopyInto(out *PowerProfile) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerProfile.
+func (in *PowerProfile) DeepCopy() *PowerProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSpec) DeepCopyInto(out *ProviderSpec) {
+	*out = *in
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.OptionsFrom != nil {
+		in, out := &in.OptionsFrom, &out.OptionsFrom
+		*out = new(ConfigMapRef)
+		**out = **in
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpec.
+func (in *ProviderSpec) DeepCopy() *ProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretRef) DeepCopyInto(out *SecretRef) {
 	*out = *in
@@ -132,3 +511,59 @@ func (in *SecretRef) DeepCopy() *SecretRef {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadEmission) DeepCopyInto(out *WorkloadEmission) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadEmission.
+func (in *WorkloadEmission) DeepCopy() *WorkloadEmission {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadEmission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneEmission) DeepCopyInto(out *ZoneEmission) {
+	*out = *in
+	if in.IntensityTime != nil {
+		in, out := &in.IntensityTime, &out.IntensityTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneEmission.
+func (in *ZoneEmission) DeepCopy() *ZoneEmission {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneEmission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneMappingSpec) DeepCopyInto(out *ZoneMappingSpec) {
+	*out = *in
+	if in.ByRegion != nil {
+		in, out := &in.ByRegion, &out.ByRegion
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneMappingSpec.
+func (in *ZoneMappingSpec) DeepCopy() *ZoneMappingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneMappingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,268 @@
+package v1beta1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/utils"
+)
+
+// ConvertTo converts this CarbonEstimator to the hub version (v1alpha1).
+func (src *CarbonEstimator) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.CarbonEstimator)
+
+	dst.ObjectMeta = src.ObjectMeta
+	src.Spec.DeepCopyInto(&dst.Spec)
+
+	status := src.Status
+	dst.Status = v1alpha1.CarbonEstimatorStatus{
+		State:               status.State,
+		ErrorMessage:        status.ErrorMessage,
+		Consumption:         format(status.Power, 2),
+		FacilityConsumption: format(status.FacilityPower, 2),
+		Emission:            format(status.EmissionRate, 2),
+		EmbodiedEmission:    format(status.EmbodiedEmissionRate, 2),
+		Zone:                status.Zone,
+		CarbonIntensity:     format(status.CarbonIntensity, 2),
+		SignalType:          status.SignalType,
+		IntensityIndex:      status.IntensityIndex,
+		IntensityForecast:   format(status.IntensityForecast, 2),
+		IntensityActual:     format(status.IntensityActual, 2),
+		IntensitySource:     status.IntensitySource,
+		IntensityTime:       status.IntensityTime.DeepCopy(),
+		EnergyTotal:         format(status.Energy, -1),
+		EmissionTotal:       format(status.Emissions, -1),
+		LastSampleTime:      status.LastSampleTime.DeepCopy(),
+		ConsecutiveFailures: status.ConsecutiveFailures,
+		NextRetryTime:       status.NextRetryTime.DeepCopy(),
+		ObservedGeneration:  status.ObservedGeneration,
+		LastUpdateTime:      status.LastUpdateTime.DeepCopy(),
+	}
+	if status.IntensityAge != nil {
+		dst.Status.IntensityAge = status.IntensityAge.Duration.String()
+	}
+	for _, c := range status.Conditions {
+		// only reports the conversion of the stored status
+		if c.Type == ConditionStatusConverted {
+			continue
+		}
+		dst.Status.Conditions = append(dst.Status.Conditions, *c.DeepCopy())
+	}
+
+	if forecast := status.Forecast; forecast != nil {
+		dst.Status.Forecast = &v1alpha1.CarbonIntensityForecast{
+			Source:     forecast.Source,
+			Model:      forecast.Model,
+			UpdateTime: forecast.UpdateTime,
+		}
+		if mape := forecast.MeanAbsolutePercentageError; mape != nil {
+			dst.Status.Forecast.MeanAbsolutePercentageError = formatQuantity(*mape, 4)
+		}
+		for _, point := range forecast.Points {
+			dst.Status.Forecast.Points = append(dst.Status.Forecast.Points, v1alpha1.ForecastPoint{
+				Time:  point.Time,
+				Value: formatQuantity(point.Value, 2),
+			})
+		}
+	}
+
+	for _, z := range status.Zones {
+		dst.Status.Zones = append(dst.Status.Zones, v1alpha1.ZoneEmission{
+			Zone:            z.Zone,
+			Nodes:           z.Nodes,
+			CarbonIntensity: format(&z.CarbonIntensity, 2),
+			Power:           format(&z.Power, 2),
+			Emission:        format(&z.EmissionRate, 2),
+			IntensitySource: z.IntensitySource,
+			IntensityTime:   z.IntensityTime.DeepCopy(),
+		})
+	}
+	for _, n := range status.Namespaces {
+		dst.Status.Namespaces = append(dst.Status.Namespaces, v1alpha1.NamespaceEmission{
+			Namespace:        n.Namespace,
+			Share:            formatQuantity(n.Share, 4),
+			Power:            format(&n.Power, 2),
+			Emission:         format(&n.EmissionRate, 2),
+			EmbodiedEmission: format(n.EmbodiedEmissionRate, 2),
+		})
+	}
+	for _, w := range status.Workloads {
+		dst.Status.Workloads = append(dst.Status.Workloads, v1alpha1.WorkloadEmission{
+			Kind:             w.Kind,
+			Name:             w.Name,
+			Namespace:        w.Namespace,
+			Share:            formatQuantity(w.Share, 4),
+			Power:            format(&w.Power, 2),
+			Emission:         format(&w.EmissionRate, 2),
+			EmbodiedEmission: format(w.EmbodiedEmissionRate, 2),
+		})
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1alpha1) to this version.
+func (dst *CarbonEstimator) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.CarbonEstimator)
+
+	dst.ObjectMeta = src.ObjectMeta
+	src.Spec.DeepCopyInto(&dst.Spec)
+
+	status := src.Status
+	p := &parser{}
+	dst.Status = CarbonEstimatorStatus{
+		State:                status.State,
+		ErrorMessage:         status.ErrorMessage,
+		Power:                p.measurement("consumption", status.Consumption, UnitWatts),
+		FacilityPower:        p.measurement("facilityConsumption", status.FacilityConsumption, UnitWatts),
+		EmissionRate:         p.measurement("emission", status.Emission, UnitGramsPerHour),
+		EmbodiedEmissionRate: p.measurement("embodiedEmission", status.EmbodiedEmission, UnitGramsPerHour),
+		Zone:                 status.Zone,
+		CarbonIntensity:      p.measurement("carbonIntensity", status.CarbonIntensity, UnitGramsPerKWh),
+		SignalType:           status.SignalType,
+		IntensityIndex:       status.IntensityIndex,
+		IntensityForecast:    p.measurement("intensityForecast", status.IntensityForecast, UnitGramsPerKWh),
+		IntensityActual:      p.measurement("intensityActual", status.IntensityActual, UnitGramsPerKWh),
+		IntensitySource:      status.IntensitySource,
+		IntensityTime:        status.IntensityTime.DeepCopy(),
+		Energy:               p.measurement("energyTotal", status.EnergyTotal, UnitKilowattHours),
+		Emissions:            p.measurement("emissionTotal", status.EmissionTotal, UnitGrams),
+		LastSampleTime:       status.LastSampleTime.DeepCopy(),
+		ConsecutiveFailures:  status.ConsecutiveFailures,
+		NextRetryTime:        status.NextRetryTime.DeepCopy(),
+		ObservedGeneration:   status.ObservedGeneration,
+		LastUpdateTime:       status.LastUpdateTime.DeepCopy(),
+	}
+	if status.IntensityAge != "" {
+		if age, err := time.ParseDuration(status.IntensityAge); err != nil {
+			p.invalid("intensityAge", status.IntensityAge)
+		} else {
+			dst.Status.IntensityAge = &metav1.Duration{Duration: age}
+		}
+	}
+	for _, c := range status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, *c.DeepCopy())
+	}
+
+	if forecast := status.Forecast; forecast != nil {
+		dst.Status.Forecast = &CarbonIntensityForecast{
+			Source:     forecast.Source,
+			Model:      forecast.Model,
+			UpdateTime: forecast.UpdateTime,
+			Unit:       UnitGramsPerKWh,
+		}
+		if forecast.MeanAbsolutePercentageError != "" {
+			dst.Status.Forecast.MeanAbsolutePercentageError = p.quantity(
+				"forecast.meanAbsolutePercentageError", forecast.MeanAbsolutePercentageError)
+		}
+		for _, point := range forecast.Points {
+			dst.Status.Forecast.Points = append(dst.Status.Forecast.Points, ForecastPoint{
+				Time:  point.Time,
+				Value: p.required("forecast.points.value", point.Value),
+			})
+		}
+	}
+
+	for _, z := range status.Zones {
+		dst.Status.Zones = append(dst.Status.Zones, ZoneEmission{
+			Zone:            z.Zone,
+			Nodes:           z.Nodes,
+			CarbonIntensity: Measurement{Value: p.required("zones.carbonIntensity", z.CarbonIntensity), Unit: UnitGramsPerKWh},
+			Power:           Measurement{Value: p.required("zones.power", z.Power), Unit: UnitWatts},
+			EmissionRate:    Measurement{Value: p.required("zones.emission", z.Emission), Unit: UnitGramsPerHour},
+			IntensitySource: z.IntensitySource,
+			IntensityTime:   z.IntensityTime.DeepCopy(),
+		})
+	}
+	for _, n := range status.Namespaces {
+		dst.Status.Namespaces = append(dst.Status.Namespaces, NamespaceEmission{
+			Namespace:            n.Namespace,
+			Share:                p.required("namespaces.share", n.Share),
+			Power:                Measurement{Value: p.required("namespaces.power", n.Power), Unit: UnitWatts},
+			EmissionRate:         Measurement{Value: p.required("namespaces.emission", n.Emission), Unit: UnitGramsPerHour},
+			EmbodiedEmissionRate: p.measurement("namespaces.embodiedEmission", n.EmbodiedEmission, UnitGramsPerHour),
+		})
+	}
+	for _, w := range status.Workloads {
+		dst.Status.Workloads = append(dst.Status.Workloads, WorkloadEmission{
+			Kind:                 w.Kind,
+			Name:                 w.Name,
+			Namespace:            w.Namespace,
+			Share:                p.required("workloads.share", w.Share),
+			Power:                Measurement{Value: p.required("workloads.power", w.Power), Unit: UnitWatts},
+			EmissionRate:         Measurement{Value: p.required("workloads.emission", w.Emission), Unit: UnitGramsPerHour},
+			EmbodiedEmissionRate: p.measurement("workloads.embodiedEmission", w.EmbodiedEmission, UnitGramsPerHour),
+		})
+	}
+
+	// a value that does not parse must not make the estimator unreadable, it is left unset and reported
+	if len(p.fields) > 0 {
+		meta.SetStatusCondition(&dst.Status.Conditions, metav1.Condition{
+			Type:               ConditionStatusConverted,
+			Status:             metav1.ConditionFalse,
+			Reason:             "InvalidStatusValue",
+			Message:            "unable to parse " + strings.Join(p.fields, ", ") + ", left unset",
+			ObservedGeneration: src.Generation,
+		})
+	}
+	return nil
+}
+
+// parser parses the formatted values of the v1alpha1 status, recording the values that do not parse.
+type parser struct {
+	fields []string
+}
+
+func (p *parser) invalid(field, value string) {
+	p.fields = append(p.fields, fmt.Sprintf("status.%s %q", field, value))
+}
+
+// measurement parses a formatted value of the given unit. Empty values, and the "-1" that older
+// controllers wrote on errors, are unknown.
+func (p *parser) measurement(field, value, unit string) *Measurement {
+	if value == "" || value == utils.ErrorInt {
+		return nil
+	}
+	q := p.quantity(field, value)
+	if q == nil {
+		return nil
+	}
+	return &Measurement{Value: *q, Unit: unit}
+}
+
+// required parses a formatted value that is always set, zero if it does not parse.
+func (p *parser) required(field, value string) resource.Quantity {
+	if q := p.quantity(field, value); q != nil {
+		return *q
+	}
+	return resource.Quantity{}
+}
+
+func (p *parser) quantity(field, value string) *resource.Quantity {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		p.invalid(field, value)
+		return nil
+	}
+	return &q
+}
+
+// format formats a measurement the way the v1alpha1 status does, decimals -1 keeping full precision.
+func format(m *Measurement, decimals int) string {
+	if m == nil {
+		return ""
+	}
+	return formatQuantity(m.Value, decimals)
+}
+
+func formatQuantity(q resource.Quantity, decimals int) string {
+	return strconv.FormatFloat(q.AsApproximateFloat64(), 'f', decimals, 64)
+}
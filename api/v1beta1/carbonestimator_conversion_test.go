@@ -0,0 +1,134 @@
+//go:build unit
+// +build unit
+
+package v1beta1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sustain_kube/api/v1alpha1"
+)
+
+func TestConvertFrom_TypedMeasurements(t *testing.T) {
+	hub := &v1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec:       v1alpha1.CarbonEstimatorSpec{Zone: "DE"},
+		Status: v1alpha1.CarbonEstimatorStatus{
+			State:           "Normal",
+			Consumption:     "120.50",
+			Emission:        "48.20",
+			CarbonIntensity: "400.00",
+			EnergyTotal:     "1.234567",
+			IntensityAge:    "5m0s",
+			Zones: []v1alpha1.ZoneEmission{{
+				Zone: "DE", Nodes: 2, CarbonIntensity: "400.00", Power: "120.50", Emission: "48.20",
+			}},
+			Namespaces: []v1alpha1.NamespaceEmission{{
+				Namespace: "default", Share: "0.2500", Power: "30.13", Emission: "12.05",
+			}},
+		},
+	}
+
+	ce := &CarbonEstimator{}
+	if err := ce.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	if ce.Spec.Zone != "DE" || ce.Name != "ce" {
+		t.Fatalf("unexpected spec or metadata: %+v", ce)
+	}
+	status := ce.Status
+	if status.Power == nil || status.Power.Value.String() != "120500m" || status.Power.Unit != UnitWatts {
+		t.Fatalf("unexpected power: %+v", status.Power)
+	}
+	if status.EmissionRate == nil || status.EmissionRate.Unit != UnitGramsPerHour {
+		t.Fatalf("unexpected emission rate: %+v", status.EmissionRate)
+	}
+	if status.Energy == nil || status.Energy.Value.AsApproximateFloat64() != 1.234567 {
+		t.Fatalf("unexpected energy: %+v", status.Energy)
+	}
+	if status.IntensityAge == nil || status.IntensityAge.Duration != 5*time.Minute {
+		t.Fatalf("unexpected intensity age: %+v", status.IntensityAge)
+	}
+	if status.FacilityPower != nil || status.Emissions != nil {
+		t.Fatalf("expected unset measurements to be nil: %+v", status)
+	}
+	if len(status.Namespaces) != 1 || status.Namespaces[0].Share.AsApproximateFloat64() != 0.25 {
+		t.Fatalf("unexpected namespaces: %+v", status.Namespaces)
+	}
+
+	// converting back yields the formatted values of v1alpha1
+	back := &v1alpha1.CarbonEstimator{}
+	if err := ce.ConvertTo(back); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	if back.Status.Consumption != "120.50" || back.Status.EnergyTotal != "1.234567" ||
+		back.Status.IntensityAge != "5m0s" || back.Status.FacilityConsumption != "" {
+		t.Fatalf("unexpected round trip: %+v", back.Status)
+	}
+	if back.Status.Zones[0].Emission != "48.20" || back.Status.Namespaces[0].Share != "0.2500" {
+		t.Fatalf("unexpected breakdown after round trip: %+v %+v", back.Status.Zones, back.Status.Namespaces)
+	}
+}
+
+func TestConvertFrom_LegacyErrorValues(t *testing.T) {
+	hub := &v1alpha1.CarbonEstimator{Status: v1alpha1.CarbonEstimatorStatus{
+		State:       "Error",
+		Consumption: "-1",
+		Emission:    "-1",
+	}}
+
+	ce := &CarbonEstimator{}
+	if err := ce.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	if ce.Status.Power != nil || ce.Status.EmissionRate != nil {
+		t.Fatalf("expected the error values to be unknown: %+v", ce.Status)
+	}
+
+	if meta.FindStatusCondition(ce.Status.Conditions, ConditionStatusConverted) != nil {
+		t.Fatalf("unexpected conversion condition: %+v", ce.Status.Conditions)
+	}
+}
+
+func TestConvertFrom_InvalidValues(t *testing.T) {
+	hub := &v1alpha1.CarbonEstimator{Status: v1alpha1.CarbonEstimatorStatus{
+		State:        "Normal",
+		Consumption:  "not a number",
+		Emission:     "48.20",
+		IntensityAge: "5 minutes",
+		Zones:        []v1alpha1.ZoneEmission{{Zone: "DE", CarbonIntensity: "400.00", Power: "n/a", Emission: "48.20"}},
+	}}
+
+	// the estimator stays readable, the invalid values are left unset and reported
+	ce := &CarbonEstimator{}
+	if err := ce.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	if ce.Status.Power != nil || ce.Status.IntensityAge != nil || !ce.Status.Zones[0].Power.Value.IsZero() {
+		t.Fatalf("expected the invalid values to be unset: %+v", ce.Status)
+	}
+	if ce.Status.EmissionRate == nil || ce.Status.Zones[0].CarbonIntensity.Value.String() != "400" {
+		t.Fatalf("expected the valid values to be converted: %+v", ce.Status)
+	}
+	condition := meta.FindStatusCondition(ce.Status.Conditions, ConditionStatusConverted)
+	if condition == nil || condition.Status != metav1.ConditionFalse ||
+		!strings.Contains(condition.Message, "status.consumption") ||
+		!strings.Contains(condition.Message, "status.intensityAge") ||
+		!strings.Contains(condition.Message, "status.zones.power") {
+		t.Fatalf("unexpected conversion condition: %+v", condition)
+	}
+
+	// the condition is not written back to the stored version
+	back := &v1alpha1.CarbonEstimator{}
+	if err := ce.ConvertTo(back); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	if meta.FindStatusCondition(back.Status.Conditions, ConditionStatusConverted) != nil {
+		t.Fatalf("unexpected conversion condition after round trip: %+v", back.Status.Conditions)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sustain_kube/api/v1alpha1"
+)
+
+// Units of the measurements of the status.
+const (
+	UnitWatts         = "W"
+	UnitKilowattHours = "kWh"
+	UnitGrams         = "gCO2eq"
+	UnitGramsPerHour  = "gCO2eq/h"
+	UnitGramsPerKWh   = "gCO2eq/kWh"
+)
+
+// ConditionStatusConverted is set to False when values of the stored v1alpha1 status could not be parsed.
+// They are left unset and the rest of the status is served. The condition is not stored.
+const ConditionStatusConverted = "StatusConverted"
+
+// Measurement is a measured amount and its unit.
+type Measurement struct {
+	// Value of the measurement
+	Value resource.Quantity `json:"value"`
+	// Unit of the value, e.g. W, kWh, gCO2eq/h
+	Unit string `json:"unit"`
+}
+
+// CarbonEstimatorStatus defines the observed state of CarbonEstimator. Unlike v1alpha1, measurements are
+// typed with their unit, and a failed reconcile leaves the last good measurements in place.
+type CarbonEstimatorStatus struct {
+	// State is Normal, Warning or Critical by power consumption, or Error when the last reconcile failed
+	// +optional
+	State string `json:"state,omitempty"`
+	// ErrorMessage of the last failed reconcile
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// Power is the IT power consumption
+	// +optional
+	Power *Measurement `json:"power,omitempty"`
+	// FacilityPower is the IT power consumption including the datacenter overhead (PUE, cooling,
+	// networking). Emissions are computed from it.
+	// +optional
+	FacilityPower *Measurement `json:"facilityPower,omitempty"`
+	// EmissionRate is the operational emission rate
+	// +optional
+	EmissionRate *Measurement `json:"emissionRate,omitempty"`
+	// EmbodiedEmissionRate is the embodied emission rate of the nodes amortized over their lifespan
+	// +optional
+	EmbodiedEmissionRate *Measurement `json:"embodiedEmissionRate,omitempty"`
+
+	// Zone is the grid zone the carbon intensity was fetched for
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// CarbonIntensity of the zone
+	// +optional
+	CarbonIntensity *Measurement `json:"carbonIntensity,omitempty"`
+	// SignalType of the carbon intensity, average or marginal (MOER)
+	// +optional
+	SignalType string `json:"signalType,omitempty"`
+	// IntensityIndex is the relative level of the carbon intensity reported by the provider, if any
+	// +optional
+	IntensityIndex string `json:"intensityIndex,omitempty"`
+	// IntensityForecast and IntensityActual are the forecast and measured carbon intensity, for providers
+	// reporting them separately
+	// +optional
+	IntensityForecast *Measurement `json:"intensityForecast,omitempty"`
+	// +optional
+	IntensityActual *Measurement `json:"intensityActual,omitempty"`
+	// IntensitySource is the provider the carbon intensity comes from, or lastKnownGood when every
+	// provider failed and the last known good value is used
+	// +optional
+	IntensitySource string `json:"intensitySource,omitempty"`
+	// IntensityTime is when the carbon intensity in use was fetched
+	// +optional
+	IntensityTime *metav1.Time `json:"intensityTime,omitempty"`
+	// IntensityAge is the age of the carbon intensity in use
+	// +optional
+	IntensityAge *metav1.Duration `json:"intensityAge,omitempty"`
+	// Forecast is the hourly carbon intensity forecast of the zone, set when spec.forecast is
+	// +optional
+	Forecast *CarbonIntensityForecast `json:"forecast,omitempty"`
+
+	// Energy is the cumulative facility energy consumed since the estimator was created
+	// +optional
+	Energy *Measurement `json:"energy,omitempty"`
+	// Emissions are the cumulative emissions since the estimator was created
+	// +optional
+	Emissions *Measurement `json:"emissions,omitempty"`
+	// LastSampleTime is when the power consumption was last measured successfully
+	// +optional
+	LastSampleTime *metav1.Time `json:"lastSampleTime,omitempty"`
+
+	// Zones is the breakdown of the facility power and emission rate per grid zone, set when spec.zones is
+	// +optional
+	Zones []ZoneEmission `json:"zones,omitempty"`
+	// Namespaces is the breakdown of the top namespaces by attributed power
+	// +optional
+	Namespaces []NamespaceEmission `json:"namespaces,omitempty"`
+	// Workloads is the breakdown of the top workloads by attributed power
+	// +optional
+	Workloads []WorkloadEmission `json:"workloads,omitempty"`
+
+	// ConsecutiveFailures is the number of reconciles that failed in a row since the last successful one
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+	// NextRetryTime is when a failed estimator is reconciled again. Transient errors back off exponentially
+	// with the consecutive failures, permanent errors (e.g. rejected credentials or an invalid query) are
+	// retried once the estimator or a referenced object changes, or after the maximum backoff.
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was last computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastUpdateTime is when the status was last written by the controller
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Conditions describe the latest observations of the estimator's dependencies. Ready is True once
+	// the power and emissions are up to date.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CarbonIntensityForecast is a carbon intensity forecast of the grid zone.
+type CarbonIntensityForecast struct {
+	// Source is the provider that published the forecast, or builtin when it is estimated from
+	// the intensity history
+	Source string `json:"source"`
+	// Model is the built-in model of builtin forecasts
+	// +optional
+	Model string `json:"model,omitempty"`
+	// UpdateTime is when the forecast was retrieved
+	UpdateTime metav1.Time `json:"updateTime"`
+	// MeanAbsolutePercentageError of the forecasts of the last day against the actual intensity, as a ratio
+	// +optional
+	MeanAbsolutePercentageError *resource.Quantity `json:"meanAbsolutePercentageError,omitempty"`
+	// Unit of the forecast values
+	Unit string `json:"unit"`
+	// Points are the forecast hours, sorted by time
+	// +optional
+	Points []ForecastPoint `json:"points,omitempty"`
+}
+
+// ForecastPoint is the forecast carbon intensity of an hour.
+type ForecastPoint struct {
+	// Time is the start of the hour
+	Time metav1.Time `json:"time"`
+	// Value is the forecast carbon intensity
+	Value resource.Quantity `json:"value"`
+}
+
+// ZoneEmission is the power and emission of the nodes of the estimator in a grid zone.
+type ZoneEmission struct {
+	Zone string `json:"zone"`
+	// Nodes is the number of nodes in the zone
+	Nodes int32 `json:"nodes"`
+	// CarbonIntensity of the zone
+	CarbonIntensity Measurement `json:"carbonIntensity"`
+	// Power is the facility power consumption of the nodes of the zone
+	Power Measurement `json:"power"`
+	// EmissionRate of the nodes of the zone
+	EmissionRate Measurement `json:"emissionRate"`
+	// IntensitySource is the provider the carbon intensity of the zone comes from, or lastKnownGood
+	// +optional
+	IntensitySource string `json:"intensitySource,omitempty"`
+	// IntensityTime is when the carbon intensity of the zone was fetched
+	// +optional
+	IntensityTime *metav1.Time `json:"intensityTime,omitempty"`
+}
+
+// NamespaceEmission is the power and emission attributed to a namespace.
+type NamespaceEmission struct {
+	Namespace string `json:"namespace"`
+	// Share is the fraction of the estimator's power, between 0 and 1
+	Share resource.Quantity `json:"share"`
+	// Power attributed to the namespace
+	Power Measurement `json:"power"`
+	// EmissionRate attributed to the namespace
+	EmissionRate Measurement `json:"emissionRate"`
+	// EmbodiedEmissionRate is the share of the embodied emission rate, set when spec.embodied is
+	// +optional
+	EmbodiedEmissionRate *Measurement `json:"embodiedEmissionRate,omitempty"`
+}
+
+// WorkloadEmission is the power and emission attributed to a workload.
+type WorkloadEmission struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Share is the fraction of the estimator's power, between 0 and 1
+	Share resource.Quantity `json:"share"`
+	// Power attributed to the workload
+	Power Measurement `json:"power"`
+	// EmissionRate attributed to the workload
+	EmissionRate Measurement `json:"emissionRate"`
+	// EmbodiedEmissionRate is the share of the embodied emission rate, set when spec.embodied is
+	// +optional
+	EmbodiedEmissionRate *Measurement `json:"embodiedEmissionRate,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// CarbonEstimator is the Schema for the carbonestimators API. The spec is unchanged from v1alpha1.
+type CarbonEstimator struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   v1alpha1.CarbonEstimatorSpec `json:"spec,omitempty"`
+	Status CarbonEstimatorStatus        `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CarbonEstimatorList contains a list of CarbonEstimator.
+type CarbonEstimatorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CarbonEstimator `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CarbonEstimator{}, &CarbonEstimatorList{})
+}
@@ -0,0 +1,328 @@
+//go:build !ignore_autogenerated
+
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CarbonEstimator) DeepCopyInto(out *CarbonEstimator) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonEstimator.
+func (in *CarbonEstimator) DeepCopy() *CarbonEstimator {
+	if in == nil {
+		return nil
+	}
+	out := new(CarbonEstimator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CarbonEstimator) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CarbonEstimatorList) DeepCopyInto(out *CarbonEstimatorList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CarbonEstimator, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonEstimatorList.
+func (in *CarbonEstimatorList) DeepCopy() *CarbonEstimatorList {
+	if in == nil {
+		return nil
+	}
+	out := new(CarbonEstimatorList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CarbonEstimatorList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CarbonEstimatorStatus) DeepCopyInto(out *CarbonEstimatorStatus) {
+	*out = *in
+	if in.Power != nil {
+		in, out := &in.Power, &out.Power
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FacilityPower != nil {
+		in, out := &in.FacilityPower, &out.FacilityPower
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EmissionRate != nil {
+		in, out := &in.EmissionRate, &out.EmissionRate
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EmbodiedEmissionRate != nil {
+		in, out := &in.EmbodiedEmissionRate, &out.EmbodiedEmissionRate
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CarbonIntensity != nil {
+		in, out := &in.CarbonIntensity, &out.CarbonIntensity
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IntensityForecast != nil {
+		in, out := &in.IntensityForecast, &out.IntensityForecast
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IntensityActual != nil {
+		in, out := &in.IntensityActual, &out.IntensityActual
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IntensityTime != nil {
+		in, out := &in.IntensityTime, &out.IntensityTime
+		*out = (*in).DeepCopy()
+	}
+	if in.IntensityAge != nil {
+		in, out := &in.IntensityAge, &out.IntensityAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(CarbonIntensityForecast)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Energy != nil {
+		in, out := &in.Energy, &out.Energy
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Emissions != nil {
+		in, out := &in.Emissions, &out.Emissions
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastSampleTime != nil {
+		in, out := &in.LastSampleTime, &out.LastSampleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]ZoneEmission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceEmission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make([]WorkloadEmission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonEstimatorStatus.
+func (in *CarbonEstimatorStatus) DeepCopy() *CarbonEstimatorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CarbonEstimatorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CarbonIntensityForecast) DeepCopyInto(out *CarbonIntensityForecast) {
+	*out = *in
+	in.UpdateTime.DeepCopyInto(&out.UpdateTime)
+	if in.MeanAbsolutePercentageError != nil {
+		in, out := &in.MeanAbsolutePercentageError, &out.MeanAbsolutePercentageError
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Points != nil {
+		in, out := &in.Points, &out.Points
+		*out = make([]ForecastPoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonIntensityForecast.
+func (in *CarbonIntensityForecast) DeepCopy() *CarbonIntensityForecast {
+	if in == nil {
+		return nil
+	}
+	out := new(CarbonIntensityForecast)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForecastPoint) DeepCopyInto(out *ForecastPoint) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	out.Value = in.Value.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForecastPoint.
+func (in *ForecastPoint) DeepCopy() *ForecastPoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ForecastPoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Measurement) DeepCopyInto(out *Measurement) {
+	*out = *in
+	out.Value = in.Value.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Measurement.
+func (in *Measurement) DeepCopy() *Measurement {
+	if in == nil {
+		return nil
+	}
+	out := new(Measurement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceEmission) DeepCopyInto(out *NamespaceEmission) {
+	*out = *in
+	out.Share = in.Share.DeepCopy()
+	in.Power.DeepCopyInto(&out.Power)
+	in.EmissionRate.DeepCopyInto(&out.EmissionRate)
+	if in.EmbodiedEmissionRate != nil {
+		in, out := &in.EmbodiedEmissionRate, &out.EmbodiedEmissionRate
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceEmission.
+func (in *NamespaceEmission) DeepCopy() *NamespaceEmission {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceEmission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadEmission) DeepCopyInto(out *WorkloadEmission) {
+	*out = *in
+	out.Share = in.Share.DeepCopy()
+	in.Power.DeepCopyInto(&out.Power)
+	in.EmissionRate.DeepCopyInto(&out.EmissionRate)
+	if in.EmbodiedEmissionRate != nil {
+		in, out := &in.EmbodiedEmissionRate, &out.EmbodiedEmissionRate
+		*out = new(Measurement)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadEmission.
+func (in *WorkloadEmission) DeepCopy() *WorkloadEmission {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadEmission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneEmission) DeepCopyInto(out *ZoneEmission) {
+	*out = *in
+	in.CarbonIntensity.DeepCopyInto(&out.CarbonIntensity)
+	in.Power.DeepCopyInto(&out.Power)
+	in.EmissionRate.DeepCopyInto(&out.EmissionRate)
+	if in.IntensityTime != nil {
+		in, out := &in.IntensityTime, &out.IntensityTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneEmission.
+func (in *ZoneEmission) DeepCopy() *ZoneEmission {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneEmission)
+	in.DeepCopyInto(out)
+	return out
+}
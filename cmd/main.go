@@ -19,7 +19,10 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -36,8 +39,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	sustainkubecomv1beta1 "sustain_kube/api/v1beta1"
 	"sustain_kube/internal/controller"
 	"sustain_kube/internal/controller/metrics"
+	"sustain_kube/internal/controller/provider"
+	"sustain_kube/internal/utils"
 
 	ctrlMetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	// +kubebuilder:scaffold:imports
@@ -52,6 +58,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(sustainkubecomv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(sustainkubecomv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -61,6 +68,11 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var allowedZones string
+	var intensityCacheTTL time.Duration
+	var defaultReconcileInterval time.Duration
+	var providerRateLimits string
+	var providerQuotas string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -72,6 +84,18 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&allowedZones, "allowed-zones", "",
+		"Comma separated list of grid zones CarbonEstimators may query (e.g. TW,JP). Leave empty to allow any zone.")
+	flag.DurationVar(&defaultReconcileInterval, "default-reconcile-interval", utils.DefaultReconcileInterval,
+		"Interval between two reconciles of CarbonEstimators without spec.interval, at least "+
+			utils.MinReconcileInterval.String()+".")
+	flag.DurationVar(&intensityCacheTTL, "intensity-cache-ttl", 5*time.Minute,
+		"How long a carbon intensity reading is shared across CarbonEstimators of the same provider and zone. "+
+			"Use 0 to disable caching.")
+	flag.StringVar(&providerRateLimits, "provider-rate-limits", "",
+		"Comma separated provider=requests-per-second limits of the carbon intensity APIs (e.g. electricitymaps=0.5).")
+	flag.StringVar(&providerQuotas, "provider-quotas", "",
+		"Comma separated provider=requests/period quotas of the carbon intensity APIs (e.g. electricitymaps=1000/24h).")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -147,14 +171,54 @@ func main() {
 
 	customMetrics := metrics.SetupMetrics("sustain_kube").MustRegister(ctrlMetrics.Registry)
 
+	var zones []string
+	for _, zone := range strings.Split(allowedZones, ",") {
+		if zone = strings.TrimSpace(zone); zone != "" {
+			zones = append(zones, zone)
+		}
+	}
+
+	if defaultReconcileInterval < utils.MinReconcileInterval {
+		setupLog.Error(fmt.Errorf("must be at least %s", utils.MinReconcileInterval),
+			"invalid --default-reconcile-interval")
+		os.Exit(1)
+	}
+
+	rateLimits, err := provider.ParseRateLimits(providerRateLimits)
+	if err != nil {
+		setupLog.Error(err, "invalid --provider-rate-limits")
+		os.Exit(1)
+	}
+	quotas, err := provider.ParseQuotas(providerQuotas)
+	if err != nil {
+		setupLog.Error(err, "invalid --provider-quotas")
+		os.Exit(1)
+	}
+	intensityCache := provider.NewCache(provider.CacheOptions{
+		TTL:        intensityCacheTTL,
+		RateLimits: rateLimits,
+		Quotas:     quotas,
+		Observer:   &customMetrics,
+	})
+
 	if err = (&controller.CarbonEstimatorReconciler{
-		Client:  mgr.GetClient(),
-		Scheme:  mgr.GetScheme(),
-		Metrics: customMetrics,
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Metrics:         customMetrics,
+		AllowedZones:    zones,
+		DefaultInterval: defaultReconcileInterval,
+		IntensityCache:  intensityCache,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CarbonEstimator")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&sustainkubecomv1alpha1.CarbonEstimator{}).SetupWebhookWithManager(mgr, zones); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CarbonEstimator")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,106 @@
+package attribution
+
+import (
+	"sort"
+)
+
+// Usage is the resource usage of a consumer (e.g. a namespace) the power is split across.
+type Usage struct {
+	// CPU usage in cores
+	CPU float64
+	// Memory working set in bytes
+	Memory float64
+}
+
+// Share is the power attributed to a single consumer.
+type Share struct {
+	Key   string
+	Watts float64
+	// Fraction of the total power, between 0 and 1
+	Fraction float64
+}
+
+// Split divides totalWatts across the consumers proportionally to their usage. cpuWeight (0-1) is the
+// part of the power split by CPU usage, the remainder is split by memory usage. If one of the resources
+// has no usage at all, the power is split by the other one only.
+//
+// The result is sorted by descending power.
+func Split(totalWatts float64, usage map[string]Usage, cpuWeight float64) []Share {
+	var cpuTotal, memoryTotal float64
+	for _, u := range usage {
+		cpuTotal += u.CPU
+		memoryTotal += u.Memory
+	}
+
+	switch {
+	case cpuTotal == 0 && memoryTotal == 0:
+		return nil
+	case cpuTotal == 0:
+		cpuWeight = 0
+	case memoryTotal == 0:
+		cpuWeight = 1
+	}
+
+	shares := make([]Share, 0, len(usage))
+	for key, u := range usage {
+		var fraction float64
+		if cpuTotal > 0 {
+			fraction += cpuWeight * u.CPU / cpuTotal
+		}
+		if memoryTotal > 0 {
+			fraction += (1 - cpuWeight) * u.Memory / memoryTotal
+		}
+		shares = append(shares, Share{
+			Key:      key,
+			Watts:    totalWatts * fraction,
+			Fraction: fraction,
+		})
+	}
+
+	sortShares(shares)
+
+	return shares
+}
+
+// Measured splits totalWatts across the consumers proportionally to the power measured for them
+// (e.g. by Kepler), so that the shares add up to totalWatts even when it includes overheads the
+// measurements do not cover.
+//
+// The result is sorted by descending power.
+func Measured(totalWatts float64, watts map[string]float64) []Share {
+	var measuredTotal float64
+	for _, w := range watts {
+		measuredTotal += w
+	}
+	if measuredTotal == 0 {
+		return nil
+	}
+
+	shares := make([]Share, 0, len(watts))
+	for key, w := range watts {
+		fraction := w / measuredTotal
+		shares = append(shares, Share{Key: key, Watts: totalWatts * fraction, Fraction: fraction})
+	}
+
+	sortShares(shares)
+
+	return shares
+}
+
+// sortShares orders shares by descending power, ties by key.
+func sortShares(shares []Share) {
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Watts == shares[j].Watts {
+			return shares[i].Key < shares[j].Key
+		}
+		return shares[i].Watts > shares[j].Watts
+	})
+}
+
+// Top returns at most n shares. Shares must already be sorted, as returned by Split.
+func Top(shares []Share, n int) []Share {
+	if n <= 0 || len(shares) <= n {
+		return shares
+	}
+	return shares[:n]
+}
@@ -0,0 +1,76 @@
+//go:build unit
+// +build unit
+
+package attribution
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSplit_WeightsCPUAndMemory(t *testing.T) {
+	usage := map[string]Usage{
+		"team-a": {CPU: 3, Memory: 1 << 30},
+		"team-b": {CPU: 1, Memory: 3 << 30},
+	}
+
+	shares := Split(100, usage, 0.5)
+	if len(shares) != 2 {
+		t.Fatalf("unexpected number of shares: %d", len(shares))
+	}
+
+	// both namespaces get 0.5*share(cpu) + 0.5*share(memory) = 50%, ties are sorted by key
+	for i, want := range []string{"team-a", "team-b"} {
+		if shares[i].Key != want || math.Abs(shares[i].Watts-50) > 1e-9 {
+			t.Fatalf("unexpected share %d: %+v", i, shares[i])
+		}
+	}
+
+	shares = Split(100, usage, 1)
+	if shares[0].Key != "team-a" || math.Abs(shares[0].Watts-75) > 1e-9 || math.Abs(shares[0].Fraction-0.75) > 1e-9 {
+		t.Fatalf("unexpected cpu-only share: %+v", shares[0])
+	}
+}
+
+func TestSplit_MissingResource(t *testing.T) {
+	usage := map[string]Usage{
+		"a": {CPU: 1},
+		"b": {CPU: 3},
+	}
+
+	// no memory usage reported, the whole power is split by CPU
+	shares := Split(40, usage, 0.5)
+	if shares[0].Key != "b" || math.Abs(shares[0].Watts-30) > 1e-9 {
+		t.Fatalf("unexpected share: %+v", shares[0])
+	}
+
+	if got := Split(40, map[string]Usage{"a": {}}, 0.5); got != nil {
+		t.Fatalf("expected no shares without usage, got %v", got)
+	}
+}
+
+func TestTop(t *testing.T) {
+	shares := []Share{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+	if got := Top(shares, 2); len(got) != 2 || got[1].Key != "b" {
+		t.Fatalf("unexpected top shares: %v", got)
+	}
+	if got := Top(shares, 0); len(got) != 3 {
+		t.Fatalf("expected all shares for n=0, got %v", got)
+	}
+}
+
+func TestMeasured(t *testing.T) {
+	// 200 W measured, 300 W including the facility overhead
+	shares := Measured(300, map[string]float64{"a": 50, "b": 150})
+
+	if shares[0].Key != "b" || math.Abs(shares[0].Watts-225) > 1e-9 || math.Abs(shares[0].Fraction-0.75) > 1e-9 {
+		t.Fatalf("unexpected share: %+v", shares[0])
+	}
+	if shares[1].Key != "a" || math.Abs(shares[1].Watts-75) > 1e-9 {
+		t.Fatalf("unexpected share: %+v", shares[1])
+	}
+
+	if got := Measured(300, map[string]float64{"a": 0}); got != nil {
+		t.Fatalf("expected no shares without measured power, got %v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package attribution
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Workload identifies the top-level object owning a pod, e.g. a Deployment or a CronJob.
+type Workload struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// String returns the workload as namespace/kind/name.
+func (w Workload) String() string {
+	return w.Namespace + "/" + w.Kind + "/" + w.Name
+}
+
+// WorkloadResolver walks owner references to find the workload owning a pod.
+// Lookups are memoized, a resolver is meant to be used for a single reconcile.
+type WorkloadResolver struct {
+	reader client.Reader
+	cache  map[types.NamespacedName]Workload
+}
+
+// NewWorkloadResolver returns a resolver reading pods, ReplicaSets and Jobs through reader.
+func NewWorkloadResolver(reader client.Reader) *WorkloadResolver {
+	return &WorkloadResolver{
+		reader: reader,
+		cache:  map[types.NamespacedName]Workload{},
+	}
+}
+
+// Resolve returns the workload owning the pod. Pods that no longer exist or have no controller are their own workload.
+//
+// Owner references are followed from ReplicaSet to Deployment and from Job to CronJob.
+// Any other controller (StatefulSet, DaemonSet, ...) is the workload itself.
+func (r *WorkloadResolver) Resolve(ctx context.Context, pod types.NamespacedName) (Workload, error) {
+	if workload, ok := r.cache[pod]; ok {
+		return workload, nil
+	}
+
+	workload := Workload{Kind: "Pod", Name: pod.Name, Namespace: pod.Namespace}
+
+	var p corev1.Pod
+	if err := r.reader.Get(ctx, pod, &p); err != nil {
+		if !errors.IsNotFound(err) {
+			return Workload{}, err
+		}
+		r.cache[pod] = workload
+		return workload, nil
+	}
+
+	if owner := metav1.GetControllerOf(&p); owner != nil {
+		var err error
+		if workload, err = r.resolveOwner(ctx, pod.Namespace, owner); err != nil {
+			return Workload{}, err
+		}
+	}
+
+	r.cache[pod] = workload
+	return workload, nil
+}
+
+func (r *WorkloadResolver) resolveOwner(ctx context.Context, namespace string, owner *metav1.OwnerReference) (Workload, error) {
+	workload := Workload{Kind: owner.Kind, Name: owner.Name, Namespace: namespace}
+	key := types.NamespacedName{Name: owner.Name, Namespace: namespace}
+
+	var obj client.Object
+	switch owner.Kind {
+	case "ReplicaSet":
+		obj = &appsv1.ReplicaSet{}
+	case "Job":
+		obj = &batchv1.Job{}
+	default:
+		return workload, nil
+	}
+
+	if err := r.reader.Get(ctx, key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return workload, nil
+		}
+		return Workload{}, err
+	}
+
+	if parent := metav1.GetControllerOf(obj); parent != nil {
+		workload.Kind = parent.Kind
+		workload.Name = parent.Name
+	}
+
+	return workload, nil
+}
+
+// ByWorkload sums pod shares, keyed by namespace/pod, per owning workload. The result is sorted by descending power.
+func ByWorkload(podShares []Share, workloads map[string]Workload) ([]Share, map[string]Workload) {
+	byKey := map[string]Workload{}
+	totals := map[string]*Share{}
+	var keys []string
+
+	for _, share := range podShares {
+		workload, ok := workloads[share.Key]
+		if !ok {
+			continue
+		}
+		key := workload.String()
+		if _, ok := totals[key]; !ok {
+			totals[key] = &Share{Key: key}
+			byKey[key] = workload
+			keys = append(keys, key)
+		}
+		totals[key].Watts += share.Watts
+		totals[key].Fraction += share.Fraction
+	}
+
+	shares := make([]Share, 0, len(keys))
+	for _, key := range keys {
+		shares = append(shares, *totals[key])
+	}
+	sortShares(shares)
+
+	return shares, byKey
+}
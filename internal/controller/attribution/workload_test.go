@@ -0,0 +1,88 @@
+//go:build unit
+// +build unit
+
+package attribution
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: ptr.To(true)}}
+}
+
+func TestWorkloadResolver_Resolve(t *testing.T) {
+	reader := fake.NewClientBuilder().WithObjects(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-5d8f", Namespace: "shop", OwnerReferences: controllerRef("Deployment", "web"),
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-5d8f-abcde", Namespace: "shop", OwnerReferences: controllerRef("ReplicaSet", "web-5d8f"),
+		}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name: "backup-28000", Namespace: "ops", OwnerReferences: controllerRef("CronJob", "backup"),
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "backup-28000-xyz", Namespace: "ops", OwnerReferences: controllerRef("Job", "backup-28000"),
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "db-0", Namespace: "shop", OwnerReferences: controllerRef("StatefulSet", "db"),
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "ops"}},
+	).Build()
+
+	resolver := NewWorkloadResolver(reader)
+
+	tests := []struct {
+		pod  types.NamespacedName
+		want Workload
+	}{
+		{types.NamespacedName{Namespace: "shop", Name: "web-5d8f-abcde"}, Workload{"Deployment", "web", "shop"}},
+		{types.NamespacedName{Namespace: "ops", Name: "backup-28000-xyz"}, Workload{"CronJob", "backup", "ops"}},
+		{types.NamespacedName{Namespace: "shop", Name: "db-0"}, Workload{"StatefulSet", "db", "shop"}},
+		{types.NamespacedName{Namespace: "ops", Name: "debug"}, Workload{"Pod", "debug", "ops"}},
+		// pods that are gone are attributed to themselves
+		{types.NamespacedName{Namespace: "ops", Name: "gone"}, Workload{"Pod", "gone", "ops"}},
+	}
+
+	for _, tt := range tests {
+		got, err := resolver.Resolve(context.Background(), tt.pod)
+		if err != nil {
+			t.Fatalf("Resolve(%s) failed: %v", tt.pod, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Resolve(%s): got %+v want %+v", tt.pod, got, tt.want)
+		}
+	}
+}
+
+func TestByWorkload(t *testing.T) {
+	podShares := []Share{
+		{Key: "shop/web-1", Watts: 30, Fraction: 0.3},
+		{Key: "shop/web-2", Watts: 30, Fraction: 0.3},
+		{Key: "shop/db-0", Watts: 40, Fraction: 0.4},
+	}
+	owners := map[string]Workload{
+		"shop/web-1": {"Deployment", "web", "shop"},
+		"shop/web-2": {"Deployment", "web", "shop"},
+		"shop/db-0":  {"StatefulSet", "db", "shop"},
+	}
+
+	shares, workloads := ByWorkload(podShares, owners)
+	if len(shares) != 2 {
+		t.Fatalf("unexpected number of workloads: %d", len(shares))
+	}
+	if workloads[shares[0].Key].Name != "web" || math.Abs(shares[0].Watts-60) > 1e-9 {
+		t.Fatalf("unexpected top workload: %+v", shares[0])
+	}
+}
@@ -18,43 +18,97 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/attribution"
+	"sustain_kube/internal/controller/energy"
+	"sustain_kube/internal/controller/forecast"
 	"sustain_kube/internal/controller/metrics"
+	"sustain_kube/internal/controller/provider"
+	"sustain_kube/internal/controller/retry"
+	"sustain_kube/internal/utils"
 
 	corev1 "k8s.io/api/core/v1"
 )
 
+// reconcileBackoff is the delay before retrying estimators after consecutive failed reconciles
+var reconcileBackoff = retry.Backoff{Base: utils.RetryBackoffBase, Max: utils.RetryBackoffMax}
+
+// estimatorPredicate ignores the updates of estimators that leave the spec unchanged, in particular the
+// status written by every reconcile, which would otherwise re-queue the estimator right away instead of
+// after its interval or failure backoff.
+var estimatorPredicate = predicate.GenerationChangedPredicate{}
+
+const (
+	// secretRefIndexKey indexes CarbonEstimators by the namespaced name of the Secret they reference
+	secretRefIndexKey = ".spec.secretRef"
+	// configMapRefIndexKey indexes CarbonEstimators by the namespaced names of the ConfigMaps they reference
+	configMapRefIndexKey = ".spec.configMapRefs"
+)
+
 // CarbonEstimatorReconciler reconciles a CarbonEstimator object
 type CarbonEstimatorReconciler struct {
 	client.Client
 	Scheme  *runtime.Scheme
 	Metrics metrics.Metrics
+
+	// AllowedZones restricts the grid zones estimators may query. Empty allows any zone.
+	AllowedZones []string
+	// DefaultInterval is the interval between two reconciles of estimators without spec.interval.
+	// SetupWithManager defaults it to 5 minutes if unset.
+	DefaultInterval time.Duration
+	// IntensityCache shares carbon intensity readings across estimators. Nil queries the provider every time.
+	IntensityCache *provider.Cache
+	// IntensityHistory collects the carbon intensity of every zone for the built-in forecast, and
+	// ForecastTracker evaluates the forecasts against it. SetupWithManager creates them if unset.
+	IntensityHistory *forecast.History
+	ForecastTracker  *forecast.Tracker
 }
 
 // +kubebuilder:rbac:groups=sustain-kube.com,resources=carbonestimators,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=sustain-kube.com,resources=carbonestimators/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=sustain-kube.com,resources=carbonestimators/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the CarbonEstimator object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
+// Reconcile measures the power of the estimator, fetches the carbon intensity of its zone and writes the
+// resulting energy, emissions and state to its status.
 //
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.1/pkg/reconcile
+// Every step records its outcome in a condition: PrometheusReachable, PowerMeasured, SecretResolved,
+// IntensityAvailable, InstanceTypesResolved, Attributed and ForecastAvailable. Ready is True once the whole
+// reconcile succeeded; a failing step sets its own condition and Ready to False with the same reason, and
+// keeps the last good measurements. Each status write records the generation it reflects in
+// observedGeneration, and when it was written in lastUpdateTime.
+//
+// A successful reconcile is requeued after the jittered interval of the estimator, and resets
+// consecutiveFailures and nextRetryTime. A failed one increments consecutiveFailures and is requeued at
+// nextRetryTime: after an exponential backoff for transient errors, or the maximum backoff for errors that
+// retrying cannot fix. Failures are returned as a requeue rather than an error, so that the backoff of the
+// work queue does not shorten the delay, and status-only updates are filtered out by estimatorPredicate.
+//
+// The metrics and forecast tracking of a deleted estimator are removed.
 func (r *CarbonEstimatorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
@@ -63,82 +117,619 @@ func (r *CarbonEstimatorReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		if errors.IsNotFound(err) {
 			log.Log.Info("CarbonEstimator resource not found")
 			r.Metrics.Delete(req)
+			r.ForecastTracker.Forget(req.String())
 		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	zone := carbonEstimator.ResolvedZone()
+	if zone == "" {
+		// estimators created without the webhook are not defaulted to an arbitrary zone
+		err := retry.Permanent(fmt.Errorf("spec.zone is not set"))
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionIntensityAvailable, "ZoneMissing", err, req)
+	}
+	if !isZoneAllowed(zone, r.AllowedZones) {
+		err := retry.Permanent(fmt.Errorf("zone %q is not in the allowed zones %v", zone, r.AllowedZones))
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionIntensityAvailable, "ZoneNotAllowed", err, req)
+	}
+
 	if err := checkPrometheusHealth(carbonEstimator.Spec.PrometheusURL); err != nil {
-		carbonEstimator.Error(err.Error())
-		_ = r.Status().Update(ctx, &carbonEstimator)
-		return ctrl.Result{}, err
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionPrometheusReachable, "PrometheusUnreachable", err, req)
 	}
+	carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionPrometheusReachable, metav1.ConditionTrue,
+		"PrometheusHealthy", fmt.Sprintf("%s is healthy", carbonEstimator.Spec.PrometheusURL))
 
-	consumption, err := calculateConsumption(
-		carbonEstimator.Spec.PrometheusURL,
-		carbonEstimator.Spec.PowerMetricQuery,
-	)
+	measurement, energyQuery, err := r.measurePower(ctx, &carbonEstimator)
+	sampleTime := time.Now()
 
 	if err != nil {
-		carbonEstimator.Error(err.Error())
-		_ = r.Status().Update(ctx, &carbonEstimator)
-		return ctrl.Result{}, err
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionPowerMeasured, "PowerMeasurementFailed", err, req)
 	}
 
-	// read electricity map token from Secret
-	var secret corev1.Secret
-	if err := r.Get(ctx, types.NamespacedName{
-		Name:      "carbon-intensity-secret",
-		Namespace: "sustain-kube-system",
-	}, &secret); err != nil {
-		carbonEstimator.Error(err.Error())
-		_ = r.Status().Update(ctx, &carbonEstimator)
-		return ctrl.Result{}, err
+	// emissions are computed from the facility power, i.e. the IT power with the datacenter overhead
+	facility, err := r.facilityPower(ctx, &carbonEstimator, measurement)
+	if err != nil {
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionPowerMeasured, "FacilityOverheadInvalid", err, req)
 	}
+	consumption := measurement.Total
+	facilityConsumption := facility.Total
+	carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionPowerMeasured, metav1.ConditionTrue, "PowerMeasured",
+		fmt.Sprintf("%d W measured by the %s", int64(math.Round(consumption)), powerSourceDescription(&carbonEstimator)))
 
-	// 解析 Secret 中的 token
-	tokenBytes, ok := secret.Data["token"]
-	if !ok {
-		carbonEstimator.Error("token not found in secret")
-		_ = r.Status().Update(ctx, &carbonEstimator)
-		return ctrl.Result{}, err
+	// embodied emissions of the nodes, amortized over their lifespan
+	var embodiedRate float64
+	if carbonEstimator.Spec.Embodied != nil {
+		if embodiedRate, err = r.embodiedEmission(ctx, &carbonEstimator); err != nil {
+			return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionReady, "EmbodiedEmissionFailed", err, req)
+		}
 	}
 
-	token := string(tokenBytes)
+	// the tokens are read once for the intensity of every zone and the forecast
+	tokens := r.resolveTokens(ctx, &carbonEstimator)
 
-	// 用token去抓carbonIntensity
-	carbonIntensity, err := getCarbonIntensity(token)
+	// 依序嘗試各 provider 抓 carbonIntensity
+	intensity, err := r.fetchIntensity(ctx, &carbonEstimator, tokens, zone)
 	if err != nil {
-		carbonEstimator.Error(err.Error())
-		_ = r.Status().Update(ctx, &carbonEstimator)
-		return ctrl.Result{}, err
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionIntensityAvailable, "IntensityUnavailable", err, req)
+	}
+
+	carbonIntensity := intensity.Value
+
+	// 存入 Status 的 CarbonIntensity, the status keeps the last known good reading as is
+	if intensity.Source != utils.IntensitySourceLastKnownGood {
+		carbonEstimator.Status.CarbonIntensity = strconv.FormatFloat(carbonIntensity, 'f', 2, 64)
+		carbonEstimator.Status.Zone = zone
+		carbonEstimator.Status.SignalType = intensity.Signal
+		carbonEstimator.Status.IntensityIndex = intensity.Index
+		carbonEstimator.Status.IntensityForecast = formatOptional(intensity.Forecast)
+		carbonEstimator.Status.IntensityActual = formatOptional(intensity.Actual)
+		carbonEstimator.Status.IntensityTime = &metav1.Time{Time: sampleTime}
+		r.IntensityHistory.Add(zone, sampleTime, carbonIntensity)
+	}
+	carbonEstimator.Status.IntensitySource = intensity.Source
+	carbonEstimator.Status.IntensityAge = sampleTime.Sub(carbonEstimator.Status.IntensityTime.Time).Round(time.Second).String()
+	if intensity.Source == utils.IntensitySourceLastKnownGood {
+		carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionIntensityAvailable, metav1.ConditionTrue, "LastKnownGood",
+			fmt.Sprintf("every provider failed, using the intensity of zone %s fetched %s ago", zone, carbonEstimator.Status.IntensityAge))
+	} else {
+		carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionIntensityAvailable, metav1.ConditionTrue, "IntensityFetched",
+			fmt.Sprintf("carbon intensity of zone %s fetched from %s", zone, intensity.Source))
 	}
 
-	// 存入 Status 的 CarbonIntensity
-	carbonEstimator.Status.CarbonIntensity = strconv.FormatFloat(carbonIntensity, 'f', 2, 64)
+	// the nodes may span several grid zones, each with its own carbon intensity
+	zones, err := r.zoneEmissions(ctx, &carbonEstimator, tokens, facility, zone, intensity)
+	if err != nil {
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionIntensityAvailable, "IntensityUnavailable", err, req)
+	}
+
+	// integrate the IT power consumption since the last successful reconcile into energy,
+	// scaled by the current facility overhead
+	var energyKWh float64
+	if previousWatts, previousTime, ok := carbonEstimator.PreviousSample(); ok {
+		energyKWh = calculateEnergy(
+			carbonEstimator.Spec.PrometheusURL,
+			energyQuery,
+			energy.Sample{Time: previousTime, Watts: previousWatts},
+			energy.Sample{Time: sampleTime, Watts: consumption},
+		)
+		if consumption > 0 {
+			energyKWh *= facilityConsumption / consumption
+		}
+	}
+
+	// the energy of the interval is split across the zones by their share of the facility power
+	var emissionGrams, emissionRate float64
+	zoneEnergy := make([]float64, len(zones))
+	zoneGrams := make([]float64, len(zones))
+	for i, z := range zones {
+		share := 1 / float64(len(zones))
+		if facilityConsumption > 0 {
+			share = z.watts / facilityConsumption
+		}
+		zoneEnergy[i] = energyKWh * share
+		zoneGrams[i] = energy.EmissionsGrams(zoneEnergy[i], z.intensity.Value)
+		emissionGrams += zoneGrams[i]
+		emissionRate += z.emissionRate()
+	}
+
+	// namespaces and workloads span the zones, they are attributed the mean intensity weighted by power
+	attributedIntensity := carbonIntensity
+	if carbonEstimator.Spec.Zones != nil && facilityConsumption > 0 {
+		attributedIntensity = emissionRate / facilityConsumption * 1000
+	}
 
 	r.Metrics.Update(
 		consumption,
-		consumption*carbonIntensity,
+		emissionRate,
 		carbonEstimator.Spec.WarningLevel,
 		carbonEstimator.Spec.CriticalLevel,
+		zone,
+		intensity.Source,
 		req)
+	r.Metrics.UpdateFacility(facilityConsumption, zone, req)
+	if samples := r.updateZones(&carbonEstimator, zones, sampleTime, zone); samples != nil {
+		r.Metrics.UpdateZones(samples, req)
+	}
 
-	carbonEstimator.UpdateStatus(consumption, consumption*carbonIntensity)
+	carbonEstimator.UpdateStatus(consumption, facilityConsumption, emissionRate)
 
-	if err := r.Status().Update(ctx, &carbonEstimator); err != nil {
-		carbonEstimator.Error(err.Error())
-		_ = r.Status().Update(ctx, &carbonEstimator)
-		return ctrl.Result{}, err
+	carbonEstimator.Status.EmbodiedEmission = ""
+	if carbonEstimator.Spec.Embodied != nil {
+		carbonEstimator.Status.EmbodiedEmission = strconv.FormatFloat(embodiedRate, 'f', 2, 64)
+		r.Metrics.UpdateEmbodied(embodiedRate, zone, req)
+	}
+
+	r.updateForecast(ctx, &carbonEstimator, tokens, zone, req)
+
+	if err := r.attribute(ctx, &carbonEstimator, facilityConsumption, attributedIntensity, embodiedRate, zone, req); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to attribute power consumption")
+		carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionAttributed, metav1.ConditionFalse,
+			"AttributionFailed", err.Error())
+	}
+	carbonEstimator.AccumulateEnergy(energyKWh, emissionGrams, sampleTime)
+	carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionReady, metav1.ConditionTrue, "Reconciled",
+		"power consumption and emissions are up to date")
+	carbonEstimator.Status.ConsecutiveFailures = 0
+	carbonEstimator.Status.NextRetryTime = nil
+
+	if err := r.updateStatus(ctx, &carbonEstimator); err != nil {
+		return r.fail(ctx, &carbonEstimator, sustainkubecomv1alpha1.ConditionReady, "StatusUpdateFailed", err, req)
+	}
+
+	// only count the interval once it is persisted, a failed update is integrated again next time
+	for i, z := range zones {
+		r.Metrics.AddEnergy(zoneEnergy[i], zoneGrams[i], z.zone, req)
 	}
 
 	log.Log.Info("Successfully reconciled CarbonEstimator")
-	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+	return ctrl.Result{RequeueAfter: r.requeueAfter(&carbonEstimator)}, nil
+}
+
+// requeueAfter returns the interval of the estimator delayed by a random jitter, so that estimators
+// created or reconciled together spread their queries to Prometheus and the providers over time.
+func (r *CarbonEstimatorReconciler) requeueAfter(carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator) time.Duration {
+	return wait.Jitter(carbonEstimator.ReconcileInterval(r.DefaultInterval), utils.ReconcileJitter)
+}
+
+// fail records a failed reconcile: the condition of the failing step, the consecutive failures and the time
+// of the next attempt in status, and the failure counter by reason. Transient errors are retried with an
+// exponential backoff, permanent ones wait for a change of the estimator or of the objects it references,
+// and are retried after the maximum backoff at the latest. The error is not returned to controller-runtime,
+// whose rate limiter would retry it within milliseconds.
+func (r *CarbonEstimatorReconciler) fail(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	conditionType, reason string,
+	err error,
+	req ctrl.Request,
+) (ctrl.Result, error) {
+	permanent := retry.IsPermanent(err)
+	carbonEstimator.Status.ConsecutiveFailures++
+	delay := reconcileBackoff.Max
+	if !permanent {
+		delay = reconcileBackoff.Delay(carbonEstimator.Status.ConsecutiveFailures)
+	}
+	delay = wait.Jitter(delay, utils.ReconcileJitter)
+
+	log.FromContext(ctx).Error(err, "Unable to reconcile CarbonEstimator",
+		"reason", reason, "permanent", permanent, "retryAfter", delay.Round(time.Second))
+	r.Metrics.ObserveFailure(reason, req)
+
+	carbonEstimator.Status.NextRetryTime = &metav1.Time{Time: time.Now().Add(delay)}
+	carbonEstimator.Error(conditionType, reason, err.Error())
+	_ = r.updateStatus(ctx, carbonEstimator)
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// updateStatus records the generation the status reflects and writes the status.
+func (r *CarbonEstimatorReconciler) updateStatus(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+) error {
+	carbonEstimator.Status.ObservedGeneration = carbonEstimator.Generation
+	carbonEstimator.Status.LastUpdateTime = &metav1.Time{Time: time.Now()}
+	return r.Status().Update(ctx, carbonEstimator)
+}
+
+// attribute splits the measured power according to the attribution mode of the estimator and
+// records the outcome in the Attributed condition. Breakdowns that are not computed are cleared.
+func (r *CarbonEstimatorReconciler) attribute(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	consumption, carbonIntensity, embodiedRate float64,
+	zone string,
+	req ctrl.Request,
+) error {
+	mode := carbonEstimator.AttributionMode()
+
+	if mode == utils.AttributionNone {
+		carbonEstimator.Status.Namespaces = nil
+		carbonEstimator.Status.Workloads = nil
+		r.Metrics.UpdateNamespaces(nil, nil, zone, req)
+		r.Metrics.UpdateWorkloads(nil, zone, req)
+		meta.RemoveStatusCondition(&carbonEstimator.Status.Conditions, sustainkubecomv1alpha1.ConditionAttributed)
+		return nil
+	}
+
+	if err := r.attributeNamespaces(carbonEstimator, consumption, carbonIntensity, embodiedRate, zone, req); err != nil {
+		carbonEstimator.Status.Namespaces = nil
+		r.Metrics.UpdateNamespaces(nil, nil, zone, req)
+		return err
+	}
+
+	if mode != utils.AttributionWorkload {
+		carbonEstimator.Status.Workloads = nil
+		r.Metrics.UpdateWorkloads(nil, zone, req)
+	} else if err := r.attributeWorkloads(ctx, carbonEstimator, consumption, carbonIntensity, embodiedRate, zone, req); err != nil {
+		carbonEstimator.Status.Workloads = nil
+		r.Metrics.UpdateWorkloads(nil, zone, req)
+		return err
+	}
+
+	carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionAttributed, metav1.ConditionTrue,
+		"Attributed", fmt.Sprintf("power consumption attributed by %s", mode))
+	return nil
+}
+
+// attributeNamespaces splits the measured power across namespaces, exports every namespace and keeps
+// the top namespaces in status.
+func (r *CarbonEstimatorReconciler) attributeNamespaces(
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	consumption, carbonIntensity, embodiedRate float64,
+	zone string,
+	req ctrl.Request,
+) error {
+	shares, err := namespaceShares(carbonEstimator, consumption)
+	if err != nil {
+		return err
+	}
+
+	power := make(map[string]float64, len(shares))
+	emission := make(map[string]float64, len(shares))
+	for _, share := range shares {
+		power[share.Key] = share.Watts
+		emission[share.Key] = energy.EmissionRate(share.Watts, carbonIntensity)
+	}
+	r.Metrics.UpdateNamespaces(power, emission, zone, req)
+
+	top := attribution.Top(shares, carbonEstimator.AttributionTopN())
+	carbonEstimator.Status.Namespaces = make([]sustainkubecomv1alpha1.NamespaceEmission, 0, len(top))
+	for _, share := range top {
+		carbonEstimator.Status.Namespaces = append(carbonEstimator.Status.Namespaces,
+			sustainkubecomv1alpha1.NamespaceEmission{
+				Namespace:        share.Key,
+				Share:            strconv.FormatFloat(share.Fraction, 'f', 4, 64),
+				Power:            strconv.FormatFloat(share.Watts, 'f', 2, 64),
+				Emission:         strconv.FormatFloat(emission[share.Key], 'f', 2, 64),
+				EmbodiedEmission: embodiedShare(carbonEstimator, embodiedRate, share.Fraction),
+			})
+	}
+
+	return nil
+}
+
+// attributeWorkloads splits the measured power across pods, rolls the pods up to the workloads owning them,
+// exports every workload and keeps the top workloads in status.
+func (r *CarbonEstimatorReconciler) attributeWorkloads(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	consumption, carbonIntensity, embodiedRate float64,
+	zone string,
+	req ctrl.Request,
+) error {
+	podShares, err := podShares(carbonEstimator, consumption)
+	if err != nil {
+		return err
+	}
+
+	resolver := attribution.NewWorkloadResolver(r.Client)
+	owners := make(map[string]attribution.Workload, len(podShares))
+	for _, share := range podShares {
+		namespace, name, _ := strings.Cut(share.Key, "/")
+		workload, err := resolver.Resolve(ctx, types.NamespacedName{Name: name, Namespace: namespace})
+		if err != nil {
+			return fmt.Errorf("unable to resolve the workload of pod %s: %w", share.Key, err)
+		}
+		owners[share.Key] = workload
+	}
+
+	shares, workloads := attribution.ByWorkload(podShares, owners)
+
+	samples := make([]metrics.Workload, 0, len(shares))
+	for _, share := range shares {
+		workload := workloads[share.Key]
+		samples = append(samples, metrics.Workload{
+			Kind:      workload.Kind,
+			Name:      workload.Name,
+			Namespace: workload.Namespace,
+			Power:     share.Watts,
+			Emission:  energy.EmissionRate(share.Watts, carbonIntensity),
+		})
+	}
+	r.Metrics.UpdateWorkloads(samples, zone, req)
+
+	top := attribution.Top(shares, carbonEstimator.AttributionTopN())
+	carbonEstimator.Status.Workloads = make([]sustainkubecomv1alpha1.WorkloadEmission, 0, len(top))
+	for i, share := range top {
+		carbonEstimator.Status.Workloads = append(carbonEstimator.Status.Workloads,
+			sustainkubecomv1alpha1.WorkloadEmission{
+				Kind:             samples[i].Kind,
+				Name:             samples[i].Name,
+				Namespace:        samples[i].Namespace,
+				Share:            strconv.FormatFloat(share.Fraction, 'f', 4, 64),
+				Power:            strconv.FormatFloat(share.Watts, 'f', 2, 64),
+				Emission:         strconv.FormatFloat(samples[i].Emission, 'f', 2, 64),
+				EmbodiedEmission: embodiedShare(carbonEstimator, embodiedRate, share.Fraction),
+			})
+	}
+
+	return nil
+}
+
+// embodiedShare formats the part of the embodied emission rate attributed to a share of the
+// cluster resources, or returns an empty string when embodied emissions are disabled.
+func embodiedShare(carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator, embodiedRate, fraction float64) string {
+	if carbonEstimator.Spec.Embodied == nil {
+		return ""
+	}
+	return strconv.FormatFloat(embodiedRate*fraction, 'f', 2, 64)
+}
+
+// namespaceShares returns the power of every namespace. Kepler measures it, otherwise the consumption
+// is split proportionally to the CPU and memory usage of the namespaces.
+func namespaceShares(
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	consumption float64,
+) ([]attribution.Share, error) {
+	if carbonEstimator.PowerSource() == utils.PowerSourceKepler {
+		watts, err := fetchKeplerNamespacePower(carbonEstimator.Spec.PrometheusURL)
+		if err != nil {
+			return nil, err
+		}
+		return attribution.Measured(consumption, watts), nil
+	}
+
+	usage, err := fetchNamespaceUsage(carbonEstimator.Spec.PrometheusURL)
+	if err != nil {
+		return nil, err
+	}
+	return attribution.Split(consumption, usage, carbonEstimator.AttributionCPUWeight()), nil
+}
+
+// podShares returns the power of every pod keyed by namespace/pod. Kepler measures it, otherwise the
+// consumption is split proportionally to the CPU and memory usage of the pods.
+func podShares(
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	consumption float64,
+) ([]attribution.Share, error) {
+	if carbonEstimator.PowerSource() == utils.PowerSourceKepler {
+		watts, err := fetchKeplerPodPower(carbonEstimator.Spec.PrometheusURL)
+		if err != nil {
+			return nil, err
+		}
+		return attribution.Measured(consumption, watts), nil
+	}
+
+	usage, err := fetchPodUsage(carbonEstimator.Spec.PrometheusURL)
+	if err != nil {
+		return nil, err
+	}
+	return attribution.Split(consumption, usage, carbonEstimator.AttributionCPUWeight()), nil
+}
+
+// providerToken is the token of a provider, or the error it could not be read with, and the reason and
+// message describing the outcome in the SecretResolved condition.
+type providerToken struct {
+	token   string
+	err     error
+	reason  string
+	message string
+}
+
+// providerTokens are the tokens of the providers of an estimator keyed by Secret and key, so that every
+// Secret is read once per reconcile whatever the providers, zones and forecasts using it. A nil
+// providerTokens queries every provider without a token.
+type providerTokens map[string]providerToken
+
+// tokenKey identifies the token referenced by ref, empty without a secretRef.
+func tokenKey(carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator, ref *sustainkubecomv1alpha1.SecretRef) string {
+	key, ok := carbonEstimator.SecretKey(ref)
+	if !ok {
+		return ""
+	}
+	return key.String() + "#" + ref.DataKey()
+}
+
+// get returns the token of the provider.
+func (tokens providerTokens) get(
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	p sustainkubecomv1alpha1.ProviderSpec,
+) providerToken {
+	return tokens[tokenKey(carbonEstimator, carbonEstimator.ProviderSecretRef(p))]
+}
+
+// resolveTokens reads the tokens of every provider of the estimator and records the outcome in the
+// SecretResolved condition, the result of every provider folded into its message. The condition is False
+// when the token of any provider cannot be read, even if the providers before it in the chain serve.
+func (r *CarbonEstimatorReconciler) resolveTokens(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+) providerTokens {
+	tokens := providerTokens{}
+	var messages []string
+	var failed string
+	referenced := false
+	for _, p := range carbonEstimator.ProviderChain() {
+		ref := carbonEstimator.ProviderSecretRef(p)
+		key := tokenKey(carbonEstimator, ref)
+		token, ok := tokens[key]
+		if !ok {
+			token = r.resolveToken(ctx, carbonEstimator, ref)
+			tokens[key] = token
+		}
+		referenced = referenced || key != ""
+		if token.err != nil && failed == "" {
+			failed = token.reason
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", p.Name, token.message))
+	}
+
+	message := strings.Join(messages, "; ")
+	switch {
+	case failed != "":
+		carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionSecretResolved, metav1.ConditionFalse,
+			failed, message)
+	case !referenced:
+		carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionSecretResolved, metav1.ConditionTrue,
+			"NoSecretReferenced", "secretRef is not set, the providers are queried without a token")
+	default:
+		carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionSecretResolved, metav1.ConditionTrue,
+			"SecretResolved", message)
+	}
+	return tokens
+}
+
+// resolveToken reads the carbon intensity API token from the Secret referenced by ref.
+// Without a secretRef the token is empty.
+func (r *CarbonEstimatorReconciler) resolveToken(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	ref *sustainkubecomv1alpha1.SecretRef,
+) providerToken {
+	key, ok := carbonEstimator.SecretKey(ref)
+	if !ok {
+		return providerToken{reason: "NoSecretReferenced", message: "queried without a token"}
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, key, &secret); err != nil {
+		reason := "SecretUnavailable"
+		switch {
+		case errors.IsNotFound(err):
+			reason = "SecretNotFound"
+		case errors.IsForbidden(err):
+			reason = "SecretForbidden"
+		}
+		err = fmt.Errorf("unable to read secret %s: %w", key, err)
+		token := providerToken{err: err, reason: reason, message: err.Error()}
+		// a missing or forbidden secret is reconciled again once it is created or its RBAC fixed
+		if reason != "SecretUnavailable" {
+			token.err = retry.Permanent(err)
+		}
+		return token
+	}
+
+	dataKey := ref.DataKey()
+	tokenBytes, ok := secret.Data[dataKey]
+	if !ok {
+		err := fmt.Errorf("key %q not found in secret %s", dataKey, key)
+		return providerToken{err: retry.Permanent(err), reason: "SecretKeyNotFound", message: err.Error()}
+	}
+
+	return providerToken{
+		token:   string(tokenBytes),
+		reason:  "SecretResolved",
+		message: fmt.Sprintf("token read from secret %s", key),
+	}
+}
+
+// readConfigMap returns the value of the key referenced by ref, defaulting the key to defaultKey.
+func (r *CarbonEstimatorReconciler) readConfigMap(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	ref *sustainkubecomv1alpha1.ConfigMapRef,
+	defaultKey string,
+) (string, error) {
+	key := carbonEstimator.ConfigMapKey(ref)
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, key, &configMap); err != nil {
+		return "", fmt.Errorf("unable to read configmap %s: %w", key, err)
+	}
+
+	dataKey := ref.Key
+	if dataKey == "" {
+		dataKey = defaultKey
+	}
+	value, ok := configMap.Data[dataKey]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in configmap %s", dataKey, key)
+	}
+	return value, nil
+}
+
+// findEstimatorsForSecret maps a Secret to the CarbonEstimators referencing it, so that
+// rotating a token re-reconciles them right away.
+func (r *CarbonEstimatorReconciler) findEstimatorsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	return r.findEstimators(ctx, secretRefIndexKey, secret)
+}
+
+// findEstimatorsForConfigMap maps a ConfigMap to the CarbonEstimators referencing it.
+func (r *CarbonEstimatorReconciler) findEstimatorsForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	return r.findEstimators(ctx, configMapRefIndexKey, configMap)
+}
+
+// findEstimators lists the CarbonEstimators referencing obj through the given index.
+func (r *CarbonEstimatorReconciler) findEstimators(ctx context.Context, indexKey string, obj client.Object) []reconcile.Request {
+	var carbonEstimators sustainkubecomv1alpha1.CarbonEstimatorList
+	if err := r.List(ctx, &carbonEstimators, client.MatchingFields{
+		indexKey: types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}.String(),
+	}); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list CarbonEstimators referencing object",
+			"index", indexKey, "object", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(carbonEstimators.Items))
+	for _, item := range carbonEstimators.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+	}
+	return requests
+}
+
+// secretRefIndex returns the namespaced names of the Secrets referenced by a CarbonEstimator.
+func secretRefIndex(obj client.Object) []string {
+	var keys []string
+	for _, key := range obj.(*sustainkubecomv1alpha1.CarbonEstimator).SecretKeys() {
+		keys = append(keys, key.String())
+	}
+	return keys
+}
+
+// configMapRefIndex returns the namespaced names of the ConfigMaps referenced by a CarbonEstimator.
+func configMapRefIndex(obj client.Object) []string {
+	var keys []string
+	for _, key := range obj.(*sustainkubecomv1alpha1.CarbonEstimator).ConfigMapKeys() {
+		keys = append(keys, key.String())
+	}
+	return keys
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *CarbonEstimatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.DefaultInterval == 0 {
+		r.DefaultInterval = utils.DefaultReconcileInterval
+	}
+	if r.IntensityHistory == nil {
+		r.IntensityHistory = forecast.NewHistory()
+	}
+	if r.ForecastTracker == nil {
+		r.ForecastTracker = forecast.NewTracker()
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(),
+		&sustainkubecomv1alpha1.CarbonEstimator{}, secretRefIndexKey, secretRefIndex); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(),
+		&sustainkubecomv1alpha1.CarbonEstimator{}, configMapRefIndexKey, configMapRefIndex); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&sustainkubecomv1alpha1.CarbonEstimator{}).
+		For(&sustainkubecomv1alpha1.CarbonEstimator{}, builder.WithPredicates(estimatorPredicate)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.findEstimatorsForSecret)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.findEstimatorsForConfigMap)).
 		Named("carbonestimator").
 		Complete(r)
 }
@@ -21,11 +21,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -120,6 +122,11 @@ var _ = Describe("CarbonEstimator Controller", func() {
 						PowerMetricQuery: "sum(node_power_watts)",
 						WarningLevel:     1,
 						CriticalLevel:    5,
+						Zone:             "JP",
+						SecretRef: &sustainkubecomv1alpha1.SecretRef{
+							Name:      "carbon-intensity-secret",
+							Namespace: "sustain-kube-system",
+						},
 					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
@@ -178,9 +185,17 @@ var _ = Describe("CarbonEstimator Controller", func() {
 
 			Expect(resource.Status.State).To(Equal("Critical")) // 100.5 > 5 (CriticalLevel)
 			Expect(resource.Status.CarbonIntensity).To(Equal("300.50"))
+			Expect(resource.Status.Zone).To(Equal("JP"))
 			Expect(resource.Status.Consumption).To(Equal("100.50")) // returns 100.5
-			Expect(resource.Status.Emission).To(Equal("30200.25"))  // 100.5 * 300.5 = 30200.25
+			Expect(resource.Status.Emission).To(Equal("30.20"))     // 100.5 W * 300.5 gCO2eq/kWh = 30.20 gCO2eq/h
+			Expect(resource.Status.EnergyTotal).To(Equal("0"))      // nothing to integrate on the first sample
+			Expect(resource.Status.LastSampleTime).NotTo(BeNil())
 			Expect(resource.Status.ErrorMessage).To(BeEmpty())
+			Expect(resource.Status.ObservedGeneration).To(Equal(resource.Generation))
+			Expect(resource.Status.LastUpdateTime).NotTo(BeNil())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, sustainkubecomv1alpha1.ConditionReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions,
+				sustainkubecomv1alpha1.ConditionIntensityAvailable)).To(BeTrue())
 		})
 
 		It("should set error status when Prometheus is unreachable", func() {
@@ -195,11 +210,12 @@ var _ = Describe("CarbonEstimator Controller", func() {
 				Metrics: metricsObj,
 			}
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
-			// Reconcile returns error, but we also want to check if Status is updated
-			Expect(err).To(HaveOccurred())
+			// the failure is retried with a backoff rather than by the rate limiter of controller-runtime
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", 30*time.Second, 3*time.Second))
 
 			By("Checking the status for error")
 			resource := &sustainkubecomv1alpha1.CarbonEstimator{}
@@ -207,6 +223,14 @@ var _ = Describe("CarbonEstimator Controller", func() {
 
 			Expect(resource.Status.State).To(Equal("Error"))
 			Expect(resource.Status.ErrorMessage).NotTo(BeEmpty())
+
+			condition := meta.FindStatusCondition(resource.Status.Conditions,
+				sustainkubecomv1alpha1.ConditionPrometheusReachable)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, sustainkubecomv1alpha1.ConditionReady)).To(BeTrue())
+			Expect(resource.Status.ConsecutiveFailures).To(Equal(int32(1)))
+			Expect(resource.Status.NextRetryTime).NotTo(BeNil())
 		})
 
 		It("should set error status when Secret is missing", func() {
@@ -226,10 +250,12 @@ var _ = Describe("CarbonEstimator Controller", func() {
 				Metrics: metricsObj,
 			}
 
-			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
-			Expect(err).To(HaveOccurred())
+			// a missing secret is permanent, the estimator is reconciled again once the secret is created
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">=", 30*time.Minute))
 
 			By("Checking the status for error")
 			resource := &sustainkubecomv1alpha1.CarbonEstimator{}
@@ -237,6 +263,13 @@ var _ = Describe("CarbonEstimator Controller", func() {
 
 			Expect(resource.Status.State).To(Equal("Error"))
 			Expect(resource.Status.ErrorMessage).NotTo(BeEmpty())
+
+			condition := meta.FindStatusCondition(resource.Status.Conditions,
+				sustainkubecomv1alpha1.ConditionSecretResolved)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("SecretNotFound"))
+			Expect(resource.Status.ConsecutiveFailures).To(Equal(int32(1)))
 		})
 
 		It("should set error status when Carbon Intensity API fails", func() {
@@ -251,10 +284,11 @@ var _ = Describe("CarbonEstimator Controller", func() {
 				Metrics: metricsObj,
 			}
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
-			Expect(err).To(HaveOccurred())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", 30*time.Second, 3*time.Second))
 
 			By("Checking the status for error")
 			resource := &sustainkubecomv1alpha1.CarbonEstimator{}
@@ -262,6 +296,11 @@ var _ = Describe("CarbonEstimator Controller", func() {
 
 			Expect(resource.Status.State).To(Equal("Error"))
 			Expect(resource.Status.ErrorMessage).NotTo(BeEmpty())
+
+			condition := meta.FindStatusCondition(resource.Status.Conditions,
+				sustainkubecomv1alpha1.ConditionIntensityAvailable)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("IntensityUnavailable"))
 		})
 	})
 })
@@ -1,18 +1,27 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"sustain_kube/internal/controller/attribution"
+	"sustain_kube/internal/controller/energy"
+	"sustain_kube/internal/controller/power"
+	"sustain_kube/internal/controller/provider"
+	"sustain_kube/internal/controller/retry"
+	"sustain_kube/internal/utils"
 )
 
+// carbonIntensityURL overrides the endpoint of the selected provider (provide to internal test)
 var carbonIntensityURL string
 
 // checkPrometheusHealth verifies if Prometheus is healthy by querying its health endpoint.
@@ -31,7 +40,7 @@ func checkPrometheusHealth(prometheusURL string) error {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Prometheus is not healthy: received status code %d", resp.StatusCode)
+		return retry.NewStatusError(resp.StatusCode, "Prometheus is not healthy: received status code %d", resp.StatusCode)
 	}
 
 	log.Log.Info("Prometheus is healthy")
@@ -53,7 +62,7 @@ func fetchPrometheusMetric(queryURL string) (float64, error) {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+		return 0, retry.NewStatusError(resp.StatusCode, "received non-200 response code: %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -94,6 +103,176 @@ func fetchPrometheusMetric(queryURL string) (float64, error) {
 	return value, nil
 }
 
+// vectorSample is a single series of an instant vector returned by Prometheus.
+type vectorSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// fetchPrometheusVector sends an instant query to Prometheus and returns every series of the resulting vector.
+func fetchPrometheusVector(prometheusURL, query string) ([]vectorSample, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/query?query=%s", prometheusURL, url.QueryEscape(query)))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching data from Prometheus: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Log.Error(err, "Error closing response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, retry.NewStatusError(resp.StatusCode, "received non-200 response code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	if result.Status != "success" {
+		return nil, fmt.Errorf("no data returned from Prometheus")
+	}
+
+	samples := make([]vectorSample, 0, len(result.Data.Result))
+	for _, series := range result.Data.Result {
+		if len(series.Value) != 2 {
+			return nil, fmt.Errorf("unexpected data format in Prometheus response")
+		}
+		valueStr, ok := series.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected data format in Prometheus response")
+		}
+		value, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing value: %v", err)
+		}
+		samples = append(samples, vectorSample{Labels: series.Metric, Value: value})
+	}
+
+	return samples, nil
+}
+
+const (
+	// namespaceCPUQuery returns the CPU cores used per namespace
+	namespaceCPUQuery = `sum by (namespace) (rate(container_cpu_usage_seconds_total{container!=""}[5m]))`
+	// namespaceMemoryQuery returns the memory working set in bytes per namespace
+	namespaceMemoryQuery = `sum by (namespace) (container_memory_working_set_bytes{container!=""})`
+	// podCPUQuery returns the CPU cores used per pod
+	podCPUQuery = `sum by (namespace, pod) (rate(container_cpu_usage_seconds_total{container!=""}[5m]))`
+	// podMemoryQuery returns the memory working set in bytes per pod
+	podMemoryQuery = `sum by (namespace, pod) (container_memory_working_set_bytes{container!=""})`
+)
+
+// fetchUsage runs the CPU and memory queries and merges their series into usage keyed by key(labels).
+// Series for which key returns an empty string are ignored.
+func fetchUsage(
+	prometheusURL, cpuQuery, memoryQuery string,
+	key func(labels map[string]string) string,
+) (map[string]attribution.Usage, error) {
+	cpu, err := fetchPrometheusVector(prometheusURL, cpuQuery)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch CPU usage: %w", err)
+	}
+	memory, err := fetchPrometheusVector(prometheusURL, memoryQuery)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch memory usage: %w", err)
+	}
+
+	usage := map[string]attribution.Usage{}
+	for _, sample := range cpu {
+		k := key(sample.Labels)
+		u := usage[k]
+		u.CPU = sample.Value
+		usage[k] = u
+	}
+	for _, sample := range memory {
+		k := key(sample.Labels)
+		u := usage[k]
+		u.Memory = sample.Value
+		usage[k] = u
+	}
+	delete(usage, "")
+
+	return usage, nil
+}
+
+// fetchNamespaceUsage returns the CPU and memory usage of every namespace reported by cAdvisor.
+func fetchNamespaceUsage(prometheusURL string) (map[string]attribution.Usage, error) {
+	return fetchUsage(prometheusURL, namespaceCPUQuery, namespaceMemoryQuery, func(labels map[string]string) string {
+		return labels["namespace"]
+	})
+}
+
+// fetchPodUsage returns the CPU and memory usage of every pod reported by cAdvisor, keyed by namespace/pod.
+func fetchPodUsage(prometheusURL string) (map[string]attribution.Usage, error) {
+	return fetchUsage(prometheusURL, podCPUQuery, podMemoryQuery, func(labels map[string]string) string {
+		if labels["namespace"] == "" || labels["pod"] == "" {
+			return ""
+		}
+		return labels["namespace"] + "/" + labels["pod"]
+	})
+}
+
+// fetchNodeUsage returns the CPU cores and memory used by the containers of every node reported
+// by cAdvisor, keyed by the value of nodeLabel.
+func fetchNodeUsage(prometheusURL, nodeLabel string) (map[string]power.NodeUsage, error) {
+	cpuQuery := fmt.Sprintf(`sum by (%s) (rate(container_cpu_usage_seconds_total{container!=""}[5m]))`, nodeLabel)
+	memoryQuery := fmt.Sprintf(`sum by (%s) (container_memory_working_set_bytes{container!=""})`, nodeLabel)
+
+	usage, err := fetchUsage(prometheusURL, cpuQuery, memoryQuery, func(labels map[string]string) string {
+		return labels[nodeLabel]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]power.NodeUsage, len(usage))
+	for node, u := range usage {
+		nodes[node] = power.NodeUsage{CPUCores: u.CPU, MemoryBytes: u.Memory}
+	}
+	return nodes, nil
+}
+
+// fetchNodeUtilization returns the CPU utilization and the memory used by every node reported
+// by node-exporter, keyed by the value of nodeLabel.
+func fetchNodeUtilization(prometheusURL, nodeLabel string) (map[string]power.NodeUsage, error) {
+	utilizationQuery := fmt.Sprintf(`1 - avg by (%s) (rate(node_cpu_seconds_total{mode="idle"}[5m]))`, nodeLabel)
+	memoryQuery := fmt.Sprintf(`sum by (%s) (node_memory_MemTotal_bytes - node_memory_MemAvailable_bytes)`, nodeLabel)
+
+	usage, err := fetchUsage(prometheusURL, utilizationQuery, memoryQuery, func(labels map[string]string) string {
+		return labels[nodeLabel]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]power.NodeUsage, len(usage))
+	for node, u := range usage {
+		nodes[node] = power.NodeUsage{CPUUtilization: u.CPU, MemoryBytes: u.Memory}
+	}
+	return nodes, nil
+}
+
+// powerQuery returns the PromQL query used to measure the power consumption of the cluster,
+// defaulting to sum(node_power_watts) when powerMetricQuery is empty.
+func powerQuery(powerMetricQuery string) string {
+	if powerMetricQuery == "" {
+		return utils.DefaultPowerQuery
+	}
+	return powerMetricQuery
+}
+
 // calculateConsumption sends an HTTP GET request to the specified Prometheus query URL
 // to get the total power consumption of the cluster. It returns the result as a float64 in Watts.
 //
@@ -102,16 +281,10 @@ func fetchPrometheusMetric(queryURL string) (float64, error) {
 //	sum(node_power_watts)
 func calculateConsumption(prometheusURL, powerMetricQuery string) (float64, error) {
 
-	query := powerMetricQuery
-	if query == "" {
-		// default fallback query assuming an exporter exposes 'node_power_watts'
-		query = "sum(node_power_watts)"
-	}
-
 	fullURL := fmt.Sprintf(
 		"%s/api/v1/query?query=%s",
 		prometheusURL,
-		url.QueryEscape(query),
+		url.QueryEscape(powerQuery(powerMetricQuery)),
 	)
 
 	consumption, err := fetchPrometheusMetric(fullURL)
@@ -123,53 +296,157 @@ func calculateConsumption(prometheusURL, powerMetricQuery string) (float64, erro
 	return consumption, nil
 }
 
-func getCarbonIntensity(token string) (float64, error) {
-	// allow overriding in tests
-	var targetURL = carbonIntensityURL // provide to internal test
-	if targetURL == "" {
-		targetURL = os.Getenv("CARBON_INTENSITY_URL") // provide to E2E test
-	}
-	if targetURL == "" {
-		targetURL = "https://api.electricitymap.org/v3/carbon-intensity/latest?zone=TW"
-	}
-
-	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("auth-token", token)
+// fetchPrometheusRange sends a range query to Prometheus and returns the samples of the
+// first series of the resulting matrix.
+func fetchPrometheusRange(prometheusURL, query string, start, end time.Time, step time.Duration) ([]energy.Sample, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatFloat(float64(start.UnixMilli())/1000, 'f', 3, 64))
+	params.Set("end", strconv.FormatFloat(float64(end.UnixMilli())/1000, 'f', 3, 64))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
 
-	// HTTP Request
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/query_range?%s", prometheusURL, params.Encode()))
 	if err != nil {
-		return 0, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("error fetching data from Prometheus: %v", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Log.Error(err, "Error closing carbon intensity API response body")
+			log.Log.Error(err, "Error closing response body")
 		}
 	}()
 
-	// 若 API 請求失敗
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("carbon intensity API error: %s", string(body))
+		return nil, retry.NewStatusError(resp.StatusCode, "received non-200 response code: %d", resp.StatusCode)
 	}
 
-	// 將回傳的 JSON 解析進 result
 	var result struct {
-		CarbonIntensity float64 `json:"carbonIntensity"`
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][]interface{}   `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read carbon intensity response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, fmt.Errorf("failed to parse carbon intensity JSON: %w", err)
+	if result.Status != "success" || result.Data.ResultType != "matrix" || len(result.Data.Result) == 0 {
+		return nil, fmt.Errorf("no data returned from Prometheus")
 	}
 
-	return result.CarbonIntensity, nil
+	values := result.Data.Result[0].Values
+	samples := make([]energy.Sample, 0, len(values))
+	for _, value := range values {
+		if len(value) != 2 {
+			return nil, fmt.Errorf("unexpected data format in Prometheus response")
+		}
+		ts, ok := value[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected data format in Prometheus response")
+		}
+		valueStr, ok := value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected data format in Prometheus response")
+		}
+		watts, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing value: %v", err)
+		}
+		samples = append(samples, energy.Sample{
+			Time:  time.UnixMilli(int64(ts * 1000)),
+			Watts: watts,
+		})
+	}
+
+	return samples, nil
+}
+
+// maxEnergyWindow bounds how far back energy is integrated after a long gap between reconciles.
+const maxEnergyWindow = 24 * time.Hour
+
+// calculateEnergy returns the energy in kWh consumed between the previous and the current reading.
+//
+// The power query is evaluated as a Prometheus range query over that interval and integrated with the
+// trapezoidal rule. When there is no query or the range query fails, the trapezoid between the two
+// readings is used instead.
+func calculateEnergy(prometheusURL, query string, previous, current energy.Sample) float64 {
+	if !current.Time.After(previous.Time) {
+		return 0
+	}
+
+	if current.Time.Sub(previous.Time) > maxEnergyWindow {
+		previous.Time = current.Time.Add(-maxEnergyWindow)
+		previous.Watts = -1
+	}
+
+	// keep the range query well below the 11000 points Prometheus allows
+	step := current.Time.Sub(previous.Time) / 250
+	if step < 15*time.Second {
+		step = 15 * time.Second
+	}
+
+	if query != "" {
+		samples, err := fetchPrometheusRange(prometheusURL, query, previous.Time, current.Time, step)
+		if err == nil && len(samples) >= 2 {
+			return energy.IntegrateKWh(samples)
+		}
+		if err != nil {
+			log.Log.Error(err, "Unable to fetch power consumption range, falling back to the last reading")
+		}
+	}
+
+	// the previous reading is unknown (e.g. after an error), assume constant power
+	if previous.Watts < 0 {
+		previous.Watts = current.Watts
+	}
+
+	return energy.IntegrateKWh([]energy.Sample{previous, current})
+}
+
+// isZoneAllowed reports whether zone is part of the allow-list. An empty allow-list allows every zone.
+func isZoneAllowed(zone string, allowedZones []string) bool {
+	if len(allowedZones) == 0 {
+		return true
+	}
+	for _, allowed := range allowedZones {
+		if strings.EqualFold(zone, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// getCarbonIntensity fetches the current carbon intensity of the given zone from the named provider,
+// through the shared cache if any.
+func getCarbonIntensity(
+	ctx context.Context,
+	cache *provider.Cache,
+	name string,
+	opts provider.Options,
+	zone string,
+) (provider.CarbonIntensity, error) {
+	// allow overriding in tests
+	if carbonIntensityURL != "" {
+		params := map[string]string{"url": carbonIntensityURL}
+		for k, v := range opts.Params {
+			if k != "url" {
+				params[k] = v
+			}
+		}
+		opts.Params = params
+	}
+
+	return cache.Fetch(ctx, name, opts, zone)
+}
+
+// formatOptional formats an optional reading for status, an absent reading is empty.
+func formatOptional(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', 2, 64)
 }
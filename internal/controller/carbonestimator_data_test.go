@@ -4,13 +4,23 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/energy"
+	"sustain_kube/internal/controller/provider"
 )
 
 func TestCheckPrometheusHealth_Success(t *testing.T) {
@@ -99,11 +109,131 @@ func TestGetCarbonIntensity_UsesOverridableURL(t *testing.T) {
 	carbonIntensityURL = ts.URL
 	defer func() { carbonIntensityURL = old }()
 
-	v, err := getCarbonIntensity("token")
+	v, err := getCarbonIntensity(context.Background(), nil, provider.ElectricityMaps, provider.Options{Token: "token"}, "TW")
 	if err != nil {
 		t.Fatalf("getCarbonIntensity failed: %v", err)
 	}
-	if v != 123.45 {
-		t.Fatalf("unexpected carbon intensity: got %v want %v", v, 123.45)
+	if v.Value != 123.45 {
+		t.Fatalf("unexpected carbon intensity: got %v want %v", v.Value, 123.45)
+	}
+	if v.Source != provider.ElectricityMaps {
+		t.Fatalf("unexpected carbon intensity source: got %v want %v", v.Source, provider.ElectricityMaps)
+	}
+}
+
+func TestIsZoneAllowed(t *testing.T) {
+	if !isZoneAllowed("TW", nil) {
+		t.Fatalf("expected any zone to be allowed with an empty allow-list")
+	}
+	if !isZoneAllowed("DE", []string{"TW", "de"}) {
+		t.Fatalf("expected DE to be allowed")
+	}
+	if isZoneAllowed("FR", []string{"TW", "DE"}) {
+		t.Fatalf("expected FR to be rejected")
+	}
+}
+
+func TestRequeueAfter_IntervalWithJitter(t *testing.T) {
+	r := &CarbonEstimatorReconciler{DefaultInterval: 10 * time.Minute}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{}
+
+	for i := 0; i < 100; i++ {
+		if d := r.requeueAfter(ce); d < 10*time.Minute || d > 11*time.Minute {
+			t.Fatalf("unexpected requeue of the default interval: %v", d)
+		}
+	}
+
+	ce.Spec.Interval = &metav1.Duration{Duration: time.Minute}
+	if d := r.requeueAfter(ce); d < time.Minute || d > 66*time.Second {
+		t.Fatalf("unexpected requeue of spec.interval: %v", d)
+	}
+
+	// intervals below the minimum are raised to it
+	ce.Spec.Interval = &metav1.Duration{Duration: time.Second}
+	if d := r.requeueAfter(ce); d < 30*time.Second || d > 33*time.Second {
+		t.Fatalf("unexpected requeue below the minimum interval: %v", d)
+	}
+}
+
+func TestCalculateEnergy_RangeQuery(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[%d,"100"],[%d,"200"],[%d,"200"]]}]}}`,
+			start.Unix(), start.Add(30*time.Minute).Unix(), start.Add(time.Hour).Unix())
+	}))
+	defer ts.Close()
+
+	got := calculateEnergy(ts.URL, "sum(node_power_watts)",
+		energy.Sample{Time: start, Watts: 100},
+		energy.Sample{Time: start.Add(time.Hour), Watts: 200})
+
+	// (100+200)/2 * 0.5h + 200 * 0.5h = 175 Wh
+	if math.Abs(got-0.175) > 1e-9 {
+		t.Fatalf("unexpected energy: got %v want %v", got, 0.175)
+	}
+}
+
+func TestCalculateEnergy_FallsBackToReadings(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := calculateEnergy(ts.URL, "sum(node_power_watts)",
+		energy.Sample{Time: start, Watts: -1},
+		energy.Sample{Time: start.Add(30 * time.Minute), Watts: 100})
+
+	// unknown previous reading, assume 100 W for half an hour
+	if math.Abs(got-0.05) > 1e-9 {
+		t.Fatalf("unexpected energy: got %v want %v", got, 0.05)
+	}
+}
+
+func TestNodeIndexLookup(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.11"},
+		}},
+	}
+	idx := nodeIndex{"worker-1": node, "10.0.0.11": node}
+
+	for _, value := range []string{"worker-1", "10.0.0.11", "10.0.0.11:9100"} {
+		if got, ok := idx.lookup(value); !ok || got.Name != "worker-1" {
+			t.Fatalf("lookup(%q) did not resolve to worker-1", value)
+		}
+	}
+	if _, ok := idx.lookup("10.0.0.12:9100"); ok {
+		t.Fatalf("expected unknown address not to resolve")
+	}
+}
+
+func TestFacilityOverhead(t *testing.T) {
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+		PUE: &sustainkubecomv1alpha1.PUESpec{
+			Value:       "1.4",
+			NodeLabel:   "datacenter",
+			ByNodeLabel: map[string]string{"dc-1": "1.1"},
+		},
+		Overhead: &sustainkubecomv1alpha1.OverheadSpec{Cooling: "0.1"},
+	}}
+
+	overhead, err := facilityOverhead(ce)
+	if err != nil {
+		t.Fatalf("facilityOverhead failed: %v", err)
+	}
+	if overhead.PUE != 1.4 || overhead.PUEByLabel["dc-1"] != 1.1 || overhead.Cooling != 0.1 || overhead.Networking != 0 {
+		t.Fatalf("unexpected overhead: %+v", overhead)
+	}
+
+	ce.Spec.PUE.ByNodeLabel["dc-2"] = "0.9"
+	if _, err := facilityOverhead(ce); err == nil {
+		t.Fatalf("expected an error for a PUE below 1")
 	}
 }
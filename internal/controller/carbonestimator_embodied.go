@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/embodied"
+	"sustain_kube/internal/controller/power"
+	"sustain_kube/internal/utils"
+)
+
+// embodiedEmission returns the embodied emission rate of the nodes of the cluster in gCO2eq/h. The
+// manufacturing emissions of every node are looked up in the instance type catalog, extended by the
+// ConfigMap referenced by spec.embodied.configMapRef, and amortized over the node lifespan.
+func (r *CarbonEstimatorReconciler) embodiedEmission(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+) (float64, error) {
+	catalog, err := r.powerCatalog(ctx, carbonEstimator)
+	if err != nil {
+		return 0, err
+	}
+
+	if ref := carbonEstimator.Spec.Embodied.ConfigMapRef; ref != nil {
+		data, err := r.readConfigMap(ctx, carbonEstimator, ref, utils.DefaultEmbodiedKey)
+		if err != nil {
+			return 0, err
+		}
+		override, err := power.ParseCatalog([]byte(data))
+		if err != nil {
+			return 0, fmt.Errorf("invalid embodied emissions in configmap %s: %w",
+				carbonEstimator.ConfigMapKey(ref), err)
+		}
+		catalog = catalog.Merge(override)
+	}
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return 0, fmt.Errorf("unable to list nodes: %w", err)
+	}
+
+	footprints := make([]embodied.Node, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		kgCO2e, lifespanYears := catalog.Embodied(node.Labels, node.Status.Capacity.Cpu().AsApproximateFloat64())
+		footprints = append(footprints, embodied.Node{KgCO2e: kgCO2e, LifespanYears: lifespanYears})
+	}
+
+	return embodied.Rate(footprints), nil
+}
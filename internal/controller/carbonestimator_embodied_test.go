@@ -0,0 +1,59 @@
+//go:build unit
+// +build unit
+
+package controller
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+)
+
+func TestEmbodiedEmission(t *testing.T) {
+	node := func(name, instanceType, cpu string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{corev1.LabelInstanceTypeStable: instanceType},
+			},
+			Status: corev1.NodeStatus{Capacity: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}
+	}
+
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().WithObjects(
+		node("worker-1", "m5.2xlarge", "8"),
+		node("worker-2", "on-prem.large", "16"),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "embodied", Namespace: "default"},
+			Data: map[string]string{"embodied.yaml": `
+instanceTypes:
+  on-prem.large: {embodiedKgCO2e: 2000, lifespanYears: 5}
+`},
+		},
+	).Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{Embodied: &sustainkubecomv1alpha1.EmbodiedSpec{
+			ConfigMapRef: &sustainkubecomv1alpha1.ConfigMapRef{Name: "embodied"},
+		}},
+	}
+
+	got, err := r.embodiedEmission(context.Background(), ce)
+	if err != nil {
+		t.Fatalf("embodiedEmission failed: %v", err)
+	}
+
+	// worker-1: 8 vCPUs * 25 kgCO2e over 4 years, worker-2: 2000 kgCO2e over 5 years
+	hoursPerYear := 365.25 * 24
+	want := 8*25*1000/(4*hoursPerYear) + 2000*1000/(5*hoursPerYear)
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("unexpected embodied emission rate: got %v want %v", got, want)
+	}
+}
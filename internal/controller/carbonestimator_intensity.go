@@ -0,0 +1,281 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/forecast"
+	"sustain_kube/internal/controller/provider"
+	"sustain_kube/internal/utils"
+)
+
+// fetchIntensity tries the providers of the estimator in turn and returns the first reading. When every
+// provider fails, the last known good intensity of the zone is returned with the lastKnownGood source,
+// as long as it is younger than spec.intensityMaxAge.
+func (r *CarbonEstimatorReconciler) fetchIntensity(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	tokens providerTokens,
+	zone string,
+) (provider.CarbonIntensity, error) {
+	var errs []error
+	for _, p := range carbonEstimator.ProviderChain() {
+		intensity, err := r.fetchProviderIntensity(ctx, carbonEstimator, tokens.get(carbonEstimator, p), p, zone)
+		if err == nil {
+			if intensity.Source == "" {
+				intensity.Source = p.Name
+			}
+			return intensity, nil
+		}
+		log.FromContext(ctx).Error(err, "Unable to fetch carbon intensity", "provider", p.Name, "zone", zone)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+	}
+
+	if intensity, ok := lastKnownIntensity(carbonEstimator, zone, time.Now()); ok {
+		return intensity, nil
+	}
+	return provider.CarbonIntensity{}, fmt.Errorf("every carbon intensity provider failed: %w", errors.Join(errs...))
+}
+
+// fetchProviderIntensity resolves the options of a provider and fetches the intensity of the zone with its token.
+func (r *CarbonEstimatorReconciler) fetchProviderIntensity(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	token providerToken,
+	p sustainkubecomv1alpha1.ProviderSpec,
+	zone string,
+) (provider.CarbonIntensity, error) {
+	if token.err != nil {
+		return provider.CarbonIntensity{}, token.err
+	}
+
+	params, err := r.providerParams(ctx, carbonEstimator, p)
+	if err != nil {
+		return provider.CarbonIntensity{}, err
+	}
+
+	return getCarbonIntensity(ctx, r.IntensityCache, p.Name, provider.Options{Token: token.token, Params: params}, zone)
+}
+
+// updateForecast retrieves the carbon intensity forecast of the zone when spec.forecast is set, or estimates
+// it from the intensity history when no provider publishes one. The forecast is stored hourly in status,
+// exported and evaluated against the actual intensity, and the outcome is recorded in the ForecastAvailable
+// condition. A failure keeps the previous forecast and does not fail the reconcile.
+func (r *CarbonEstimatorReconciler) updateForecast(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	tokens providerTokens,
+	zone string,
+	req ctrl.Request,
+) {
+	if carbonEstimator.Spec.Forecast == nil {
+		carbonEstimator.Status.Forecast = nil
+		r.Metrics.DeleteForecast(req)
+		r.ForecastTracker.Forget(req.String())
+		meta.RemoveStatusCondition(&carbonEstimator.Status.Conditions, sustainkubecomv1alpha1.ConditionForecastAvailable)
+		return
+	}
+
+	now := time.Now()
+	reason := "ForecastRetrieved"
+	source, points, err := r.fetchForecast(ctx, carbonEstimator, tokens, zone)
+	model := ""
+	if err != nil {
+		var builtinErr error
+		if points, builtinErr = r.builtinForecast(carbonEstimator, zone, now); builtinErr != nil {
+			err = fmt.Errorf("%w, built-in forecast: %w", err, builtinErr)
+			log.FromContext(ctx).Error(err, "Unable to forecast carbon intensity", "zone", zone)
+			carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionForecastAvailable, metav1.ConditionFalse,
+				"ForecastUnavailable", err.Error())
+			return
+		}
+		reason, source, model = "ForecastEstimated", utils.ForecastSourceBuiltin, carbonEstimator.ForecastModel()
+	}
+
+	hourly := provider.Hourly(points)
+	values := make(map[time.Time]float64, len(hourly))
+	samples := make([]forecast.Sample, 0, len(hourly))
+	status := &sustainkubecomv1alpha1.CarbonIntensityForecast{
+		Source:     source,
+		Model:      model,
+		UpdateTime: metav1.Time{Time: now},
+		Points:     make([]sustainkubecomv1alpha1.ForecastPoint, 0, len(hourly)),
+	}
+	for _, point := range hourly {
+		values[point.Timestamp] = point.Value
+		samples = append(samples, forecast.Sample{Time: point.Timestamp, Value: point.Value})
+		status.Points = append(status.Points, sustainkubecomv1alpha1.ForecastPoint{
+			Time:  metav1.Time{Time: point.Timestamp},
+			Value: strconv.FormatFloat(point.Value, 'f', 2, 64),
+		})
+	}
+	r.Metrics.UpdateForecast(values, now, zone, req)
+
+	// evaluate the forecast of the last complete hour, then track the new one
+	if previous := carbonEstimator.Status.Forecast; previous != nil {
+		status.MeanAbsolutePercentageError = previous.MeanAbsolutePercentageError
+	}
+	hour := now.Truncate(time.Hour).Add(-time.Hour)
+	if actual, ok := r.IntensityHistory.Mean(zone, hour); ok {
+		if ape, mape, ok := r.ForecastTracker.Evaluate(req.String(), hour, actual); ok {
+			status.MeanAbsolutePercentageError = strconv.FormatFloat(mape, 'f', 4, 64)
+			r.Metrics.UpdateForecastError(ape, zone, source, req)
+		}
+	}
+	r.ForecastTracker.Record(req.String(), samples, now)
+
+	carbonEstimator.Status.Forecast = status
+	message := fmt.Sprintf("%d hours of forecast retrieved from %s", len(hourly), source)
+	if model != "" {
+		message = fmt.Sprintf("%d hours of forecast estimated by the %s model, no provider forecast: %v",
+			len(hourly), model, err)
+	}
+	carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionForecastAvailable, metav1.ConditionTrue, reason, message)
+}
+
+// builtinForecast estimates the forecast of the zone up to the horizon from the intensity history.
+func (r *CarbonEstimatorReconciler) builtinForecast(
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	zone string,
+	now time.Time,
+) ([]provider.ForecastPoint, error) {
+	since := now.Add(-time.Duration(carbonEstimator.ForecastHistoryDays()) * 24 * time.Hour)
+	series := r.IntensityHistory.Series(zone, since)
+	start := now.Truncate(time.Hour)
+	hours := int(math.Ceil(now.Add(carbonEstimator.ForecastHorizon()).Sub(start).Hours()))
+
+	var samples []forecast.Sample
+	var err error
+	switch model := carbonEstimator.ForecastModel(); model {
+	case utils.ForecastModelSeasonalNaive:
+		samples, err = forecast.SeasonalNaive(series, start, hours)
+	case utils.ForecastModelHoltWinters:
+		samples, err = forecast.HoltWinters(series, start, hours)
+	case utils.ForecastModelNone:
+		return nil, fmt.Errorf("the built-in forecast is disabled")
+	default:
+		return nil, fmt.Errorf("unknown forecast model %q", model)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]provider.ForecastPoint, 0, len(samples))
+	for _, sample := range samples {
+		points = append(points, provider.ForecastPoint{Timestamp: sample.Time, Value: sample.Value})
+	}
+	return points, nil
+}
+
+// fetchForecast returns the forecast of the zone from the first provider of the chain publishing one.
+func (r *CarbonEstimatorReconciler) fetchForecast(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	tokens providerTokens,
+	zone string,
+) (string, []provider.ForecastPoint, error) {
+	horizon := carbonEstimator.ForecastHorizon()
+
+	var errs []error
+	for _, p := range carbonEstimator.ProviderChain() {
+		token := tokens.get(carbonEstimator, p)
+		if token.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, token.err))
+			continue
+		}
+		params, err := r.providerParams(ctx, carbonEstimator, p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+			continue
+		}
+
+		points, err := r.IntensityCache.Forecast(ctx, p.Name, provider.Options{Token: token.token, Params: params}, zone, horizon)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+			continue
+		}
+		if len(points) == 0 {
+			errs = append(errs, fmt.Errorf("%s: empty forecast", p.Name))
+			continue
+		}
+		return p.Name, points, nil
+	}
+
+	return "", nil, fmt.Errorf("no carbon intensity forecast available: %w", errors.Join(errs...))
+}
+
+// providerParams returns the options of the provider on top of the data of the ConfigMap
+// referenced by its optionsFrom.
+func (r *CarbonEstimatorReconciler) providerParams(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	p sustainkubecomv1alpha1.ProviderSpec,
+) (map[string]string, error) {
+	params := map[string]string{}
+
+	if p.OptionsFrom != nil {
+		key := carbonEstimator.ConfigMapKey(p.OptionsFrom)
+		var configMap corev1.ConfigMap
+		if err := r.Get(ctx, key, &configMap); err != nil {
+			return nil, fmt.Errorf("unable to read configmap %s: %w", key, err)
+		}
+		for k, v := range configMap.Data {
+			params[k] = v
+		}
+	}
+
+	for k, v := range p.Options {
+		params[k] = v
+	}
+	return params, nil
+}
+
+// lastKnownIntensity returns the carbon intensity of the zone kept in status, or in the breakdown per zone,
+// if it was fetched less than spec.intensityMaxAge ago.
+func lastKnownIntensity(
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	zone string,
+	now time.Time,
+) (provider.CarbonIntensity, bool) {
+	status := carbonEstimator.Status
+	known, fetched, signal, index := status.CarbonIntensity, status.IntensityTime, status.SignalType, status.IntensityIndex
+	if status.Zone != zone {
+		// the other zones of the nodes are kept in the breakdown per zone
+		known, fetched, signal, index = "", nil, "", ""
+		for _, z := range status.Zones {
+			if z.Zone == zone {
+				known, fetched = z.CarbonIntensity, z.IntensityTime
+			}
+		}
+	}
+
+	maxAge := carbonEstimator.IntensityMaxAge()
+	if maxAge <= 0 || fetched == nil || now.Sub(fetched.Time) > maxAge {
+		return provider.CarbonIntensity{}, false
+	}
+
+	value, err := strconv.ParseFloat(known, 64)
+	if err != nil || value < 0 {
+		return provider.CarbonIntensity{}, false
+	}
+
+	return provider.CarbonIntensity{
+		Value:     value,
+		Unit:      provider.UnitGramsPerKWh,
+		Timestamp: fetched.Time,
+		Source:    utils.IntensitySourceLastKnownGood,
+		Signal:    signal,
+		Index:     index,
+	}, true
+}
@@ -0,0 +1,168 @@
+//go:build unit
+// +build unit
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/forecast"
+	"sustain_kube/internal/controller/metrics"
+	"sustain_kube/internal/controller/provider"
+	"sustain_kube/internal/utils"
+)
+
+func TestFetchIntensity_FallsBackAlongTheChain(t *testing.T) {
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "grid", Namespace: "default"},
+			Data:       map[string]string{"value": "300"},
+		},
+	).Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{Providers: []sustainkubecomv1alpha1.ProviderSpec{
+			// misconfigured, watttime requires a username
+			{Name: provider.WattTime},
+			{Name: provider.Static, OptionsFrom: &sustainkubecomv1alpha1.ConfigMapRef{Name: "grid"},
+				Options: map[string]string{"value": "250"}},
+		}},
+	}
+
+	got, err := r.fetchIntensity(context.Background(), ce, nil, "TW")
+	if err != nil {
+		t.Fatalf("fetchIntensity failed: %v", err)
+	}
+	if got.Source != provider.Static || got.Value != 250 {
+		t.Fatalf("unexpected intensity: got %s %v want %s 250", got.Source, got.Value, provider.Static)
+	}
+}
+
+func TestFetchIntensity_LastKnownGood(t *testing.T) {
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().Build()}
+	newEstimator := func(fetched time.Duration) *sustainkubecomv1alpha1.CarbonEstimator {
+		return &sustainkubecomv1alpha1.CarbonEstimator{
+			ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+			Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+				Providers:       []sustainkubecomv1alpha1.ProviderSpec{{Name: provider.WattTime}},
+				IntensityMaxAge: &metav1.Duration{Duration: 30 * time.Minute},
+			},
+			Status: sustainkubecomv1alpha1.CarbonEstimatorStatus{
+				CarbonIntensity: "420.50",
+				Zone:            "TW",
+				SignalType:      provider.SignalAverage,
+				IntensityTime:   &metav1.Time{Time: time.Now().Add(-fetched)},
+			},
+		}
+	}
+
+	got, err := r.fetchIntensity(context.Background(), newEstimator(10*time.Minute), nil, "TW")
+	if err != nil {
+		t.Fatalf("fetchIntensity failed: %v", err)
+	}
+	if got.Source != utils.IntensitySourceLastKnownGood || got.Value != 420.5 {
+		t.Fatalf("unexpected intensity: got %s %v want %s 420.5", got.Source, got.Value, utils.IntensitySourceLastKnownGood)
+	}
+
+	if _, err := r.fetchIntensity(context.Background(), newEstimator(time.Hour), nil, "TW"); err == nil {
+		t.Fatalf("expected an error once the last known good intensity is older than the max age")
+	}
+	if _, err := r.fetchIntensity(context.Background(), newEstimator(10*time.Minute), nil, "JP"); err == nil {
+		t.Fatalf("expected an error for a zone without a known intensity")
+	}
+}
+
+func TestForecast_FromFirstForecaster(t *testing.T) {
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().Build(), Metrics: metrics.SetupMetrics("test")}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+			Providers: []sustainkubecomv1alpha1.ProviderSpec{
+				{Name: provider.WattTime},
+				{Name: provider.Static, Options: map[string]string{"value": "250"}},
+			},
+			Forecast: &sustainkubecomv1alpha1.ForecastSpec{Horizon: &metav1.Duration{Duration: 6 * time.Hour}},
+		},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "ce", Namespace: "default"}}
+
+	r.updateForecast(context.Background(), ce, nil, "TW", req)
+
+	forecast := ce.Status.Forecast
+	if forecast == nil || forecast.Source != provider.Static {
+		t.Fatalf("unexpected forecast: %+v", forecast)
+	}
+	// the current hour and the six next ones, the last one starting before the horizon
+	if n := len(forecast.Points); n < 6 || n > 7 || forecast.Points[0].Value != "250.00" {
+		t.Fatalf("unexpected forecast points: %+v", forecast.Points)
+	}
+	if !meta.IsStatusConditionTrue(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionForecastAvailable) {
+		t.Fatalf("expected the ForecastAvailable condition to be true: %+v", ce.Status.Conditions)
+	}
+
+	// a failing chain keeps the previous forecast
+	ce.Spec.Providers = []sustainkubecomv1alpha1.ProviderSpec{{Name: provider.WattTime}}
+	r.updateForecast(context.Background(), ce, nil, "TW", req)
+	if ce.Status.Forecast != forecast {
+		t.Fatalf("expected the previous forecast to be kept")
+	}
+	if meta.IsStatusConditionTrue(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionForecastAvailable) {
+		t.Fatalf("expected the ForecastAvailable condition to be false")
+	}
+}
+
+func TestForecast_BuiltinFromHistory(t *testing.T) {
+	r := &CarbonEstimatorReconciler{
+		Client:           fake.NewClientBuilder().Build(),
+		Metrics:          metrics.SetupMetrics("test"),
+		IntensityHistory: forecast.NewHistory(),
+		ForecastTracker:  forecast.NewTracker(),
+	}
+	now := time.Now()
+	for h := 3 * 24; h > 0; h-- {
+		r.IntensityHistory.Add("TW", now.Add(-time.Duration(h)*time.Hour), 400)
+	}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+			// watttime is misconfigured and the only provider
+			Providers: []sustainkubecomv1alpha1.ProviderSpec{{Name: provider.WattTime}},
+			Forecast: &sustainkubecomv1alpha1.ForecastSpec{
+				Horizon: &metav1.Duration{Duration: 3 * time.Hour},
+				Builtin: utils.ForecastModelHoltWinters,
+			},
+		},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "ce", Namespace: "default"}}
+
+	r.updateForecast(context.Background(), ce, nil, "TW", req)
+
+	status := ce.Status.Forecast
+	if status == nil || status.Source != utils.ForecastSourceBuiltin || status.Model != utils.ForecastModelHoltWinters {
+		t.Fatalf("unexpected forecast: %+v", status)
+	}
+	if n := len(status.Points); n < 3 || n > 4 || status.Points[len(status.Points)-1].Value != "400.00" {
+		t.Fatalf("unexpected forecast points: %+v", status.Points)
+	}
+	condition := meta.FindStatusCondition(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionForecastAvailable)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != "ForecastEstimated" {
+		t.Fatalf("unexpected ForecastAvailable condition: %+v", condition)
+	}
+
+	// the built-in forecast can be disabled
+	ce.Spec.Forecast.Builtin = utils.ForecastModelNone
+	r.updateForecast(context.Background(), ce, nil, "TW", req)
+	if meta.IsStatusConditionTrue(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionForecastAvailable) {
+		t.Fatalf("expected the ForecastAvailable condition to be false with the built-in forecast disabled")
+	}
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/power"
+)
+
+// Kepler (https://sustainable-computing.io) exports energy counters in joules, their rate is the power in W.
+const (
+	// keplerNodePlatformQuery returns the power of every node measured at the platform level
+	keplerNodePlatformQuery = `sum by (instance) (rate(kepler_node_platform_joules_total[5m]))`
+	// keplerNodeComponentsQuery returns the power of the CPU packages and DRAM of every node,
+	// for nodes without a platform power meter (e.g. virtual machines)
+	keplerNodeComponentsQuery = `sum by (instance) (rate(kepler_node_package_joules_total[5m]))` +
+		` + sum by (instance) (rate(kepler_node_dram_joules_total[5m]))`
+	// keplerNamespaceQuery returns the power of every namespace
+	keplerNamespaceQuery = `sum by (container_namespace) (rate(kepler_container_joules_total[5m]))`
+	// keplerPodQuery returns the power of every pod
+	keplerPodQuery = `sum by (container_namespace, pod_name) (rate(kepler_container_joules_total[5m]))`
+)
+
+// fetchPower runs a query returning power in W and sums its series by key(labels).
+// Series for which key returns an empty string are ignored.
+func fetchPower(prometheusURL, query string, key func(labels map[string]string) string) (map[string]float64, error) {
+	samples, err := fetchPrometheusVector(prometheusURL, query)
+	if err != nil {
+		return nil, err
+	}
+
+	watts := make(map[string]float64, len(samples))
+	for _, sample := range samples {
+		if k := key(sample.Labels); k != "" {
+			watts[k] += sample.Value
+		}
+	}
+	return watts, nil
+}
+
+// fetchKeplerNodePower returns the power of every node keyed by the Kepler instance label, and
+// the query that measured it. The platform power is preferred over the CPU package and DRAM power.
+func fetchKeplerNodePower(prometheusURL string) (map[string]float64, string, error) {
+	instance := func(labels map[string]string) string { return labels["instance"] }
+
+	for _, query := range []string{keplerNodePlatformQuery, keplerNodeComponentsQuery} {
+		watts, err := fetchPower(prometheusURL, query, instance)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to fetch Kepler node power: %w", err)
+		}
+		if len(watts) > 0 {
+			return watts, query, nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("no Kepler node energy metrics found in Prometheus")
+}
+
+// fetchKeplerNamespacePower returns the power of every namespace measured by Kepler.
+func fetchKeplerNamespacePower(prometheusURL string) (map[string]float64, error) {
+	watts, err := fetchPower(prometheusURL, keplerNamespaceQuery, func(labels map[string]string) string {
+		return labels["container_namespace"]
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch Kepler namespace power: %w", err)
+	}
+	return watts, nil
+}
+
+// fetchKeplerPodPower returns the power of every pod measured by Kepler, keyed by namespace/pod.
+func fetchKeplerPodPower(prometheusURL string) (map[string]float64, error) {
+	watts, err := fetchPower(prometheusURL, keplerPodQuery, func(labels map[string]string) string {
+		if labels["container_namespace"] == "" || labels["pod_name"] == "" {
+			return ""
+		}
+		return labels["container_namespace"] + "/" + labels["pod_name"]
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch Kepler pod power: %w", err)
+	}
+	return watts, nil
+}
+
+// measureKeplerPower returns the power of every node measured by Kepler, keyed by node name, and the
+// query reproducing the cluster power over time.
+func (r *CarbonEstimatorReconciler) measureKeplerPower(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+) (power.Measurement, string, error) {
+	watts, query, err := fetchKeplerNodePower(carbonEstimator.Spec.PrometheusURL)
+	if err != nil {
+		return power.Measurement{}, "", err
+	}
+
+	nodes, err := r.nodeIndex(ctx)
+	if err != nil {
+		return power.Measurement{}, "", err
+	}
+
+	byNode := make(map[string]float64, len(watts))
+	for instance, w := range watts {
+		name := instance
+		if node, ok := nodes.lookup(instance); ok {
+			name = node.Name
+		}
+		byNode[name] += w
+	}
+
+	return power.NewMeasurement(byNode), fmt.Sprintf("sum(%s)", query), nil
+}
@@ -0,0 +1,138 @@
+//go:build unit
+// +build unit
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/utils"
+)
+
+// fakeKepler serves instant vectors per query, queries without series return an empty vector.
+func fakeKepler(t *testing.T, series map[string][]map[string]string, values map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("query")
+
+		type sample struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		}
+		result := []sample{}
+		for i, labels := range series[q] {
+			result = append(result, sample{Metric: labels, Value: []interface{}{1700000000, values[q][i]}})
+		}
+
+		resp := map[string]interface{}{
+			"status": "success",
+			"data":   map[string]interface{}{"resultType": "vector", "result": result},
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Errorf("unable to encode response: %v", err)
+		}
+	}))
+}
+
+func TestFetchKeplerNodePower_FallsBackToComponents(t *testing.T) {
+	ts := fakeKepler(t,
+		map[string][]map[string]string{keplerNodeComponentsQuery: {{"instance": "worker-1"}}},
+		map[string][]string{keplerNodeComponentsQuery: {"42.5"}},
+	)
+	defer ts.Close()
+
+	watts, query, err := fetchKeplerNodePower(ts.URL)
+	if err != nil {
+		t.Fatalf("fetchKeplerNodePower failed: %v", err)
+	}
+	if query != keplerNodeComponentsQuery || watts["worker-1"] != 42.5 {
+		t.Fatalf("unexpected node power %v from query %q", watts, query)
+	}
+}
+
+func TestFetchKeplerNodePower_NoMetrics(t *testing.T) {
+	ts := fakeKepler(t, nil, nil)
+	defer ts.Close()
+
+	if _, _, err := fetchKeplerNodePower(ts.URL); err == nil {
+		t.Fatalf("expected an error without Kepler metrics")
+	}
+}
+
+func TestMeasureKeplerPower(t *testing.T) {
+	ts := fakeKepler(t,
+		map[string][]map[string]string{keplerNodePlatformQuery: {{"instance": "10.0.0.11:9102"}, {"instance": "worker-2"}}},
+		map[string][]string{keplerNodePlatformQuery: {"120", "80.5"}},
+	)
+	defer ts.Close()
+
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().WithObjects(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.11"},
+		}},
+	}).Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+		PrometheusURL: ts.URL,
+		PowerSource:   utils.PowerSourceKepler,
+	}}
+
+	m, query, err := r.measurePower(context.Background(), ce)
+	if err != nil {
+		t.Fatalf("measurePower failed: %v", err)
+	}
+	if m.Nodes["worker-1"] != 120 || m.Nodes["worker-2"] != 80.5 || math.Abs(m.Total-200.5) > 1e-9 {
+		t.Fatalf("unexpected measurement: %+v", m)
+	}
+	if query != "sum("+keplerNodePlatformQuery+")" {
+		t.Fatalf("unexpected energy query: %q", query)
+	}
+}
+
+func TestKeplerShares(t *testing.T) {
+	ts := fakeKepler(t,
+		map[string][]map[string]string{
+			keplerNamespaceQuery: {{"container_namespace": "shop"}, {"container_namespace": "ops"}},
+			keplerPodQuery: {
+				{"container_namespace": "shop", "pod_name": "web-0"},
+				{"container_namespace": "shop"},
+			},
+		},
+		map[string][]string{
+			keplerNamespaceQuery: {"30", "10"},
+			keplerPodQuery:       {"25", "5"},
+		},
+	)
+	defer ts.Close()
+
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+		PrometheusURL: ts.URL,
+		PowerSource:   utils.PowerSourceKepler,
+	}}
+
+	shares, err := namespaceShares(ce, 40)
+	if err != nil {
+		t.Fatalf("namespaceShares failed: %v", err)
+	}
+	if len(shares) != 2 || shares[0].Key != "shop" || shares[0].Watts != 30 || math.Abs(shares[0].Fraction-0.75) > 1e-9 {
+		t.Fatalf("unexpected namespace shares: %+v", shares)
+	}
+
+	shares, err = podShares(ce, 25)
+	if err != nil {
+		t.Fatalf("podShares failed: %v", err)
+	}
+	// the series without a pod name is dropped
+	if len(shares) != 1 || shares[0].Key != "shop/web-0" || shares[0].Watts != 25 {
+		t.Fatalf("unexpected pod shares: %+v", shares)
+	}
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/power"
+	"sustain_kube/internal/controller/retry"
+	"sustain_kube/internal/utils"
+)
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+// measurePower returns the power consumption of the cluster according to the power model of the estimator,
+// and the PromQL query that reproduces it over time, if any, for energy integration.
+func (r *CarbonEstimatorReconciler) measurePower(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+) (power.Measurement, string, error) {
+	if carbonEstimator.PowerSource() == utils.PowerSourceKepler {
+		return r.measureKeplerPower(ctx, carbonEstimator)
+	}
+
+	switch model := carbonEstimator.PowerModelType(); model {
+	case utils.PowerModelQuery:
+		consumption, err := calculateConsumption(
+			carbonEstimator.Spec.PrometheusURL,
+			carbonEstimator.Spec.PowerMetricQuery,
+		)
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+		return power.Measurement{Total: consumption}, powerQuery(carbonEstimator.Spec.PowerMetricQuery), nil
+
+	case utils.PowerModelCoefficients:
+		classes, err := coefficientClasses(carbonEstimator.Spec.PowerModel.Coefficients)
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+		series, err := fetchNodeUsage(carbonEstimator.Spec.PrometheusURL, carbonEstimator.PowerModelNodeLabel())
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+		usage, err := r.resolveNodes(ctx, series)
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+		measurement, err := power.CoefficientsModel(usage, classes)
+		return measurement, "", err
+
+	case utils.PowerModelIdleMax:
+		profiles, err := powerProfiles(carbonEstimator.Spec.PowerModel.Profiles)
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+		catalog, err := r.powerCatalog(ctx, carbonEstimator)
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+		series, err := fetchNodeUtilization(carbonEstimator.Spec.PrometheusURL, carbonEstimator.PowerModelNodeLabel())
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+		usage, err := r.resolveNodes(ctx, series)
+		if err != nil {
+			return power.Measurement{}, "", err
+		}
+
+		measurement, unmatched := power.IdleMaxModel(usage, profiles, catalog)
+		if len(unmatched) > 0 {
+			carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionInstanceTypesResolved, metav1.ConditionFalse,
+				"DefaultProfileUsed", fmt.Sprintf("no power profile in catalog %s matches nodes %v, "+
+					"their power is estimated from their vCPUs", catalog.Version, unmatched))
+		} else {
+			carbonEstimator.SetCondition(sustainkubecomv1alpha1.ConditionInstanceTypesResolved, metav1.ConditionTrue,
+				"InstanceTypesResolved", fmt.Sprintf("every node matches a power profile of catalog %s", catalog.Version))
+		}
+		return measurement, "", nil
+
+	default:
+		return power.Measurement{}, "", fmt.Errorf("unknown power model %q", model)
+	}
+}
+
+// powerSourceDescription describes where the power readings of the estimator come from.
+func powerSourceDescription(carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator) string {
+	if carbonEstimator.PowerSource() == utils.PowerSourceKepler {
+		return "kepler power source"
+	}
+	return carbonEstimator.PowerModelType() + " power model"
+}
+
+// facilityPower applies the PUE and overhead factors of the spec to the IT power measurement.
+func (r *CarbonEstimatorReconciler) facilityPower(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	it power.Measurement,
+) (power.Measurement, error) {
+	overhead, err := facilityOverhead(carbonEstimator)
+	if err != nil {
+		return power.Measurement{}, retry.Permanent(err)
+	}
+
+	// node labels are only needed to pick a per-node PUE
+	var nodeLabels map[string]map[string]string
+	if overhead.NodeLabel != "" && len(it.Nodes) > 0 {
+		nodes, err := r.nodeIndex(ctx)
+		if err != nil {
+			return power.Measurement{}, err
+		}
+		nodeLabels = make(map[string]map[string]string, len(it.Nodes))
+		for name := range it.Nodes {
+			if node, ok := nodes.lookup(name); ok {
+				nodeLabels[name] = node.Labels
+			}
+		}
+	}
+
+	return overhead.Apply(it, nodeLabels), nil
+}
+
+// facilityOverhead parses the PUE and overhead factors of the spec.
+func facilityOverhead(carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator) (power.Overhead, error) {
+	var overhead power.Overhead
+
+	if pue := carbonEstimator.Spec.PUE; pue != nil {
+		var err error
+		if pue.Value != "" {
+			if overhead.PUE, err = parsePUE(pue.Value); err != nil {
+				return power.Overhead{}, err
+			}
+		}
+		overhead.NodeLabel = pue.NodeLabel
+		overhead.PUEByLabel = make(map[string]float64, len(pue.ByNodeLabel))
+		for value, s := range pue.ByNodeLabel {
+			if overhead.PUEByLabel[value], err = parsePUE(s); err != nil {
+				return power.Overhead{}, fmt.Errorf("%w for %s=%s", err, pue.NodeLabel, value)
+			}
+		}
+	}
+
+	if factors := carbonEstimator.Spec.Overhead; factors != nil {
+		var err error
+		if factors.Cooling != "" {
+			if overhead.Cooling, err = strconv.ParseFloat(factors.Cooling, 64); err != nil {
+				return power.Overhead{}, fmt.Errorf("invalid cooling overhead: %w", err)
+			}
+		}
+		if factors.Networking != "" {
+			if overhead.Networking, err = strconv.ParseFloat(factors.Networking, 64); err != nil {
+				return power.Overhead{}, fmt.Errorf("invalid networking overhead: %w", err)
+			}
+		}
+	}
+
+	return overhead, nil
+}
+
+// parsePUE parses a PUE, which is at least 1 by definition.
+func parsePUE(s string) (float64, error) {
+	pue, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pue: %w", err)
+	}
+	if pue < 1 {
+		return 0, fmt.Errorf("invalid pue %s: must be at least 1", s)
+	}
+	return pue, nil
+}
+
+// powerCatalog returns the built-in instance type catalog, merged with the one referenced by spec.powerModel.catalogRef.
+func (r *CarbonEstimatorReconciler) powerCatalog(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+) (power.Catalog, error) {
+	catalog := power.DefaultCatalog()
+
+	if carbonEstimator.Spec.PowerModel == nil || carbonEstimator.Spec.PowerModel.CatalogRef == nil {
+		return catalog, nil
+	}
+	ref := carbonEstimator.Spec.PowerModel.CatalogRef
+
+	data, err := r.readConfigMap(ctx, carbonEstimator, ref, utils.DefaultCatalogKey)
+	if err != nil {
+		return power.Catalog{}, err
+	}
+	override, err := power.ParseCatalog([]byte(data))
+	if err != nil {
+		return power.Catalog{}, fmt.Errorf("invalid instance type catalog in configmap %s: %w",
+			carbonEstimator.ConfigMapKey(ref), err)
+	}
+
+	return catalog.Merge(override), nil
+}
+
+// powerProfiles parses the power profiles of the spec.
+func powerProfiles(specs []sustainkubecomv1alpha1.PowerProfile) ([]power.Profile, error) {
+	profiles := make([]power.Profile, 0, len(specs))
+	for i, spec := range specs {
+		idle, err := strconv.ParseFloat(spec.IdleWatts, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid idle watts of power profile %d: %w", i, err)
+		}
+		maximum, err := strconv.ParseFloat(spec.MaxWatts, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max watts of power profile %d: %w", i, err)
+		}
+		var memory float64
+		if spec.MemoryWattsPerGB != "" {
+			if memory, err = strconv.ParseFloat(spec.MemoryWattsPerGB, 64); err != nil {
+				return nil, fmt.Errorf("invalid memory watts of power profile %d: %w", i, err)
+			}
+		}
+		if maximum < idle {
+			return nil, fmt.Errorf("max watts of power profile %d is lower than its idle watts", i)
+		}
+		profiles = append(profiles, power.Profile{
+			Selector:    labels.SelectorFromSet(spec.NodeSelector),
+			IdleWatts:   idle,
+			MaxWatts:    maximum,
+			MemoryWatts: memory,
+		})
+	}
+
+	return profiles, nil
+}
+
+// coefficientClasses parses the node class coefficients of the spec.
+func coefficientClasses(specs []sustainkubecomv1alpha1.NodeClassCoefficients) ([]power.Coefficients, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("the coefficients power model requires at least one node class")
+	}
+
+	classes := make([]power.Coefficients, 0, len(specs))
+	for i, spec := range specs {
+		cpu, err := strconv.ParseFloat(spec.CPU, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu coefficient of node class %d: %w", i, err)
+		}
+		memory, err := strconv.ParseFloat(spec.Memory, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory coefficient of node class %d: %w", i, err)
+		}
+		classes = append(classes, power.Coefficients{
+			Selector: labels.SelectorFromSet(spec.NodeSelector),
+			CPU:      cpu,
+			Memory:   memory,
+		})
+	}
+
+	return classes, nil
+}
+
+// resolveNodes maps usage series keyed by a node name or address to the Nodes of the cluster,
+// keying them by node name and attaching the node labels. Series of unknown nodes keep their key.
+func (r *CarbonEstimatorReconciler) resolveNodes(
+	ctx context.Context,
+	series map[string]power.NodeUsage,
+) (map[string]power.NodeUsage, error) {
+	nodes, err := r.nodeIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]power.NodeUsage, len(series))
+	for key, u := range series {
+		name := key
+		if node, ok := nodes.lookup(key); ok {
+			name, u.Labels = node.Name, node.Labels
+			u.CPUCapacity = node.Status.Capacity.Cpu().AsApproximateFloat64()
+		}
+
+		nodeUsage := usage[name]
+		nodeUsage.Labels = u.Labels
+		nodeUsage.CPUCores += u.CPUCores
+		nodeUsage.CPUCapacity = u.CPUCapacity
+		nodeUsage.CPUUtilization = math.Max(nodeUsage.CPUUtilization, u.CPUUtilization)
+		nodeUsage.MemoryBytes += u.MemoryBytes
+		usage[name] = nodeUsage
+	}
+
+	return usage, nil
+}
+
+// nodeIndex finds Nodes by name or address.
+type nodeIndex map[string]*corev1.Node
+
+// nodeIndex lists the Nodes of the cluster.
+func (r *CarbonEstimatorReconciler) nodeIndex(ctx context.Context) (nodeIndex, error) {
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+
+	index := nodeIndex{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		index[node.Name] = node
+		for _, address := range node.Status.Addresses {
+			if _, exists := index[address.Address]; !exists {
+				index[address.Address] = node
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// lookup returns the Node a Prometheus label value refers to. The value is either a node name,
+// or an address with an optional port such as the instance label of node-exporter.
+func (idx nodeIndex) lookup(value string) (*corev1.Node, bool) {
+	if node, ok := idx[value]; ok {
+		return node, true
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		node, ok := idx[host]
+		return node, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,227 @@
+//go:build unit
+// +build unit
+
+package controller
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/forecast"
+	"sustain_kube/internal/controller/metrics"
+	"sustain_kube/internal/controller/provider"
+)
+
+// fakePrometheus serves a constant 100 W without range queries, so that the energy is integrated from
+// the readings. An unhealthy Prometheus fails its health check.
+func fakePrometheus(t *testing.T, healthy bool) *httptest.Server {
+	t.Helper()
+	prom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !healthy:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/-/healthy":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/api/v1/query":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"100"]}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(prom.Close)
+	return prom
+}
+
+// newTestReconciler returns a reconciler of an estimator of the static 250 gCO2eq/kWh intensity querying
+// the given Prometheus, and the fake client holding the estimator.
+func newTestReconciler(t *testing.T, prometheusURL string) (*CarbonEstimatorReconciler, client.Client, ctrl.Request) {
+	t.Helper()
+	log.SetLogger(logr.Discard())
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = sustainkubecomv1alpha1.AddToScheme(scheme)
+
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default", Generation: 1},
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+			PrometheusURL:    prometheusURL,
+			WarningLevel:     200,
+			CriticalLevel:    300,
+			PowerMetricQuery: "sum(node_power_watts)",
+			Zone:             "TW",
+			Provider: &sustainkubecomv1alpha1.ProviderSpec{
+				Name: provider.Static, Options: map[string]string{"value": "250"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ce).WithStatusSubresource(ce).Build()
+	r := &CarbonEstimatorReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		Metrics:          metrics.SetupMetrics("test"),
+		DefaultInterval:  5 * time.Minute,
+		IntensityHistory: forecast.NewHistory(),
+		ForecastTracker:  forecast.NewTracker(),
+	}
+	return r, c, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ce)}
+}
+
+func TestReconcile_AccumulatesTotals(t *testing.T) {
+	r, c, req := newTestReconciler(t, fakePrometheus(t, true).URL)
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{}
+
+	// the first reconcile has no previous sample to integrate from
+	for i, want := range []struct{ energyKWh, emissionGrams float64 }{{0, 0}, {0.1, 25}, {0.2, 50}} {
+		if i > 0 {
+			// the previous reconcile happened an hour ago, give or take the duration of the test
+			if err := c.Get(context.Background(), req.NamespacedName, ce); err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			ce.Status.LastSampleTime = &metav1.Time{Time: ce.Status.LastSampleTime.Add(-time.Hour)}
+			if err := c.Status().Update(context.Background(), ce); err != nil {
+				t.Fatalf("status update failed: %v", err)
+			}
+		}
+
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		if err := c.Get(context.Background(), req.NamespacedName, ce); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+
+		energyTotal, _ := strconv.ParseFloat(ce.Status.EnergyTotal, 64)
+		emissionTotal, _ := strconv.ParseFloat(ce.Status.EmissionTotal, 64)
+		if math.Abs(energyTotal-want.energyKWh) > 1e-3 || math.Abs(emissionTotal-want.emissionGrams) > 0.5 {
+			t.Fatalf("unexpected totals after reconcile %d: got %s kWh %s g want %v kWh %v g",
+				i+1, ce.Status.EnergyTotal, ce.Status.EmissionTotal, want.energyKWh, want.emissionGrams)
+		}
+		if ce.Status.LastSampleTime == nil || time.Since(ce.Status.LastSampleTime.Time) > time.Minute {
+			t.Fatalf("expected the sample time to be updated: %v", ce.Status.LastSampleTime)
+		}
+	}
+}
+
+func TestReconcile_StatusUpdateDoesNotEnqueue(t *testing.T) {
+	r, c, req := newTestReconciler(t, fakePrometheus(t, true).URL)
+
+	before := &sustainkubecomv1alpha1.CarbonEstimator{}
+	if err := c.Get(context.Background(), req.NamespacedName, before); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter < 5*time.Minute || result.RequeueAfter > 6*time.Minute {
+		t.Fatalf("expected a requeue after the default interval, got %v", result.RequeueAfter)
+	}
+	after := &sustainkubecomv1alpha1.CarbonEstimator{}
+	if err := c.Get(context.Background(), req.NamespacedName, after); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if after.Status.LastUpdateTime == nil || after.ResourceVersion == before.ResourceVersion {
+		t.Fatalf("expected the reconcile to write the status: %+v", after.Status)
+	}
+
+	// the status written by the reconcile must not trigger the next one before the interval
+	if estimatorPredicate.Update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}) {
+		t.Fatalf("expected the status update not to enqueue the estimator")
+	}
+
+	// a change of the spec is reconciled right away
+	changed := after.DeepCopy()
+	changed.Spec.WarningLevel = 250
+	changed.Generation++
+	if !estimatorPredicate.Update(event.UpdateEvent{ObjectOld: after, ObjectNew: changed}) {
+		t.Fatalf("expected the spec update to enqueue the estimator")
+	}
+}
+
+func TestReconcile_FailureWaitsForNextRetryTime(t *testing.T) {
+	r, c, req := newTestReconciler(t, fakePrometheus(t, false).URL)
+
+	before := &sustainkubecomv1alpha1.CarbonEstimator{}
+	if err := c.Get(context.Background(), req.NamespacedName, before); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	start := time.Now()
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected the failure to be handled by the backoff, got %v", err)
+	}
+	after := &sustainkubecomv1alpha1.CarbonEstimator{}
+	if err := c.Get(context.Background(), req.NamespacedName, after); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	// the unavailable Prometheus is transient, retried after the base backoff. Status times are kept to
+	// the second.
+	if after.Status.ConsecutiveFailures != 1 || after.Status.NextRetryTime == nil {
+		t.Fatalf("expected the failure in status: %+v", after.Status)
+	}
+	if result.RequeueAfter < 30*time.Second || result.RequeueAfter > 33*time.Second {
+		t.Fatalf("unexpected requeue: %v", result.RequeueAfter)
+	}
+	retryAt := start.Add(result.RequeueAfter)
+	if d := after.Status.NextRetryTime.Sub(retryAt); d < -2*time.Second || d > 2*time.Second {
+		t.Fatalf("expected the requeue at the next retry time %v, got %v", after.Status.NextRetryTime, retryAt)
+	}
+
+	// writing the failure to status must not re-queue the estimator before the next retry time
+	if estimatorPredicate.Update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}) {
+		t.Fatalf("expected the status update not to enqueue the estimator")
+	}
+}
+
+func TestReconcile_ZoneMissing(t *testing.T) {
+	r, c, req := newTestReconciler(t, fakePrometheus(t, true).URL)
+
+	// an estimator created without the webhook is not defaulted to a zone
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{}
+	if err := c.Get(context.Background(), req.NamespacedName, ce); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	ce.Spec.Zone = ""
+	if err := c.Update(context.Background(), ce); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected the failure to be handled by the backoff, got %v", err)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, ce); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	condition := meta.FindStatusCondition(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionIntensityAvailable)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "ZoneMissing" {
+		t.Fatalf("unexpected condition: %+v", condition)
+	}
+	if ce.Status.Zone != "" || ce.Status.LastSampleTime != nil {
+		t.Fatalf("expected no intensity to be fetched: %+v", ce.Status)
+	}
+	// the missing zone is permanent, it is not retried before the spec changes
+	if result.RequeueAfter < 30*time.Minute {
+		t.Fatalf("expected the maximum backoff, got %v", result.RequeueAfter)
+	}
+}
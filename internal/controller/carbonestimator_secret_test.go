@@ -0,0 +1,193 @@
+//go:build unit
+// +build unit
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/provider"
+	"sustain_kube/internal/controller/retry"
+)
+
+func TestResolveTokens_ReadsEverySecretOnce(t *testing.T) {
+	var gets int
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "carbon", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("em-token")},
+		},
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			gets++
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+			SecretRef: &sustainkubecomv1alpha1.SecretRef{Name: "carbon"},
+			Providers: []sustainkubecomv1alpha1.ProviderSpec{
+				{Name: provider.ElectricityMaps},
+				{Name: provider.CarbonIntensityUK},
+				{Name: provider.Static, Options: map[string]string{"value": "250"}},
+			},
+		},
+	}
+
+	tokens := r.resolveTokens(context.Background(), ce)
+	if gets != 1 {
+		t.Fatalf("expected the shared secret to be read once, got %d reads", gets)
+	}
+	for _, p := range ce.ProviderChain() {
+		if got := tokens.get(ce, p); got.err != nil || got.token != "em-token" {
+			t.Fatalf("unexpected token of %s: %+v", p.Name, got)
+		}
+	}
+	condition := meta.FindStatusCondition(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionSecretResolved)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != "SecretResolved" {
+		t.Fatalf("unexpected condition: %+v", condition)
+	}
+
+	// a provider whose secret is missing fails the condition, the message covers every provider
+	ce.Spec.Providers[1].SecretRef = &sustainkubecomv1alpha1.SecretRef{Name: "missing"}
+	tokens = r.resolveTokens(context.Background(), ce)
+	if err := tokens.get(ce, ce.Spec.Providers[1]).err; err == nil || !retry.IsPermanent(err) {
+		t.Fatalf("expected a permanent error for the missing secret, got %v", err)
+	}
+	if err := tokens.get(ce, ce.Spec.Providers[0]).err; err != nil {
+		t.Fatalf("unexpected error for the other providers: %v", err)
+	}
+	condition = meta.FindStatusCondition(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionSecretResolved)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "SecretNotFound" {
+		t.Fatalf("unexpected condition: %+v", condition)
+	}
+	for _, name := range []string{provider.ElectricityMaps, provider.CarbonIntensityUK, provider.Static} {
+		if !strings.Contains(condition.Message, name+": ") {
+			t.Fatalf("expected the outcome of %s in the message: %q", name, condition.Message)
+		}
+	}
+}
+
+func TestResolveTokens_NoSecretReferenced(t *testing.T) {
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"}}
+
+	tokens := r.resolveTokens(context.Background(), ce)
+	if got := tokens.get(ce, ce.ProviderChain()[0]); got.err != nil || got.token != "" {
+		t.Fatalf("unexpected token: %+v", got)
+	}
+	condition := meta.FindStatusCondition(ce.Status.Conditions, sustainkubecomv1alpha1.ConditionSecretResolved)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != "NoSecretReferenced" {
+		t.Fatalf("unexpected condition: %+v", condition)
+	}
+}
+
+func TestFindEstimatorsForSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = sustainkubecomv1alpha1.AddToScheme(scheme)
+
+	estimator := func(name, namespace string, ref *sustainkubecomv1alpha1.SecretRef) client.Object {
+		return &sustainkubecomv1alpha1.CarbonEstimator{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       sustainkubecomv1alpha1.CarbonEstimatorSpec{SecretRef: ref},
+		}
+	}
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&sustainkubecomv1alpha1.CarbonEstimator{}, secretRefIndexKey, secretRefIndex).
+		WithObjects(
+			// an empty namespace refers to the namespace of the estimator
+			estimator("same-namespace", "team-a", &sustainkubecomv1alpha1.SecretRef{Name: "carbon"}),
+			estimator("other-namespace", "team-b", &sustainkubecomv1alpha1.SecretRef{Name: "carbon", Namespace: "team-a"}),
+			estimator("own-secret", "team-b", &sustainkubecomv1alpha1.SecretRef{Name: "carbon"}),
+			estimator("other-secret", "team-a", &sustainkubecomv1alpha1.SecretRef{Name: "watttime"}),
+			estimator("no-secret", "team-a", nil),
+			&sustainkubecomv1alpha1.CarbonEstimator{
+				ObjectMeta: metav1.ObjectMeta{Name: "provider-secret", Namespace: "team-a"},
+				Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{Providers: []sustainkubecomv1alpha1.ProviderSpec{
+					{Name: provider.WattTime, SecretRef: &sustainkubecomv1alpha1.SecretRef{Name: "watttime"}},
+					{Name: provider.ElectricityMaps, SecretRef: &sustainkubecomv1alpha1.SecretRef{Name: "carbon"}},
+				}},
+			},
+		).Build()}
+
+	tests := []struct {
+		secret types.NamespacedName
+		want   []string
+	}{
+		{types.NamespacedName{Name: "carbon", Namespace: "team-a"},
+			[]string{"team-a/provider-secret", "team-a/same-namespace", "team-b/other-namespace"}},
+		{types.NamespacedName{Name: "carbon", Namespace: "team-b"}, []string{"team-b/own-secret"}},
+		{types.NamespacedName{Name: "watttime", Namespace: "team-a"}, []string{"team-a/other-secret", "team-a/provider-secret"}},
+		{types.NamespacedName{Name: "unused", Namespace: "team-a"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.secret.String(), func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tt.secret.Name, Namespace: tt.secret.Namespace}}
+
+			got := []string{}
+			for _, req := range r.findEstimatorsForSecret(context.Background(), secret) {
+				got = append(got, req.String())
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("unexpected estimators: got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveToken(t *testing.T) {
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "carbon", Namespace: "team-a"},
+			Data:       map[string][]byte{"token": []byte("team-a-token"), "apiKey": []byte("team-a-key")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "carbon", Namespace: "team-b"},
+			Data:       map[string][]byte{"token": []byte("team-b-token")},
+		},
+	).Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "team-a"}}
+
+	tests := []struct {
+		name   string
+		ref    *sustainkubecomv1alpha1.SecretRef
+		token  string
+		reason string
+	}{
+		{"no secretRef", nil, "", "NoSecretReferenced"},
+		{"namespace of the estimator", &sustainkubecomv1alpha1.SecretRef{Name: "carbon"}, "team-a-token", "SecretResolved"},
+		{"other namespace", &sustainkubecomv1alpha1.SecretRef{Name: "carbon", Namespace: "team-b"}, "team-b-token", "SecretResolved"},
+		{"custom key", &sustainkubecomv1alpha1.SecretRef{Name: "carbon", Key: "apiKey"}, "team-a-key", "SecretResolved"},
+		{"missing key", &sustainkubecomv1alpha1.SecretRef{Name: "carbon", Namespace: "team-b", Key: "apiKey"}, "", "SecretKeyNotFound"},
+		{"missing secret", &sustainkubecomv1alpha1.SecretRef{Name: "watttime"}, "", "SecretNotFound"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.resolveToken(context.Background(), ce, tt.ref)
+			if got.token != tt.token || got.reason != tt.reason {
+				t.Fatalf("unexpected token: got %q (%s) want %q (%s)", got.token, got.reason, tt.token, tt.reason)
+			}
+			// a missing secret or key is not retried before the estimator or the secret changes
+			if failed := tt.token == "" && tt.ref != nil; failed != (got.err != nil) || failed != retry.IsPermanent(got.err) {
+				t.Fatalf("unexpected error: %v", got.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/energy"
+	"sustain_kube/internal/controller/metrics"
+	"sustain_kube/internal/controller/power"
+	"sustain_kube/internal/controller/provider"
+	"sustain_kube/internal/controller/retry"
+	"sustain_kube/internal/utils"
+)
+
+// zoneEmission is the facility power of the nodes of a grid zone and the carbon intensity of the zone.
+type zoneEmission struct {
+	zone      string
+	nodes     int
+	watts     float64
+	intensity provider.CarbonIntensity
+}
+
+// emissionRate returns the emission rate of the zone in gCO2eq/h.
+func (z zoneEmission) emissionRate() float64 {
+	return energy.EmissionRate(z.watts, z.intensity.Value)
+}
+
+// zoneEmissions splits the facility power across the grid zones of the nodes according to spec.zones and
+// fetches the carbon intensity of every zone. The intensity of the zone of the estimator is already known.
+// Without spec.zones, the whole power belongs to the zone of the estimator.
+func (r *CarbonEstimatorReconciler) zoneEmissions(
+	ctx context.Context,
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	tokens providerTokens,
+	facility power.Measurement,
+	zone string,
+	intensity provider.CarbonIntensity,
+) ([]zoneEmission, error) {
+	if carbonEstimator.Spec.Zones == nil {
+		return []zoneEmission{{zone: zone, nodes: len(facility.Nodes), watts: facility.Total, intensity: intensity}}, nil
+	}
+	if len(facility.Nodes) == 0 {
+		return nil, retry.Permanent(fmt.Errorf("spec.zones requires the power of every node, " +
+			"use the coefficients or idleMax power model or the kepler power source"))
+	}
+
+	nodes, err := r.nodeIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byZone := map[string]*zoneEmission{}
+	for name, watts := range facility.Nodes {
+		var nodeLabels map[string]string
+		if node, ok := nodes.lookup(name); ok {
+			nodeLabels = node.Labels
+		}
+		nodeZone := carbonEstimator.NodeZone(nodeLabels)
+
+		z, ok := byZone[nodeZone]
+		if !ok {
+			z = &zoneEmission{zone: nodeZone}
+			byZone[nodeZone] = z
+		}
+		z.nodes++
+		z.watts += watts
+	}
+
+	zones := make([]zoneEmission, 0, len(byZone))
+	for _, z := range byZone {
+		zones = append(zones, *z)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].zone < zones[j].zone })
+
+	for i := range zones {
+		if zones[i].zone == zone {
+			zones[i].intensity = intensity
+			continue
+		}
+		if !isZoneAllowed(zones[i].zone, r.AllowedZones) {
+			return nil, retry.Permanent(fmt.Errorf("zone %q of nodes in spec.zones is not in the allowed zones %v",
+				zones[i].zone, r.AllowedZones))
+		}
+		if zones[i].intensity, err = r.fetchIntensity(ctx, carbonEstimator, tokens, zones[i].zone); err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zones[i].zone, err)
+		}
+	}
+
+	return zones, nil
+}
+
+// updateZones records the fresh carbon intensity readings of the zones in the intensity history, and
+// writes the breakdown per zone to status and metrics when spec.zones is set.
+func (r *CarbonEstimatorReconciler) updateZones(
+	carbonEstimator *sustainkubecomv1alpha1.CarbonEstimator,
+	zones []zoneEmission,
+	sampleTime time.Time,
+	zone string,
+) []metrics.Zone {
+	for _, z := range zones {
+		// the reading of the zone of the estimator is recorded with the status
+		if z.zone != zone && z.intensity.Source != utils.IntensitySourceLastKnownGood {
+			r.IntensityHistory.Add(z.zone, sampleTime, z.intensity.Value)
+		}
+	}
+
+	if carbonEstimator.Spec.Zones == nil {
+		carbonEstimator.Status.Zones = nil
+		return nil
+	}
+
+	samples := make([]metrics.Zone, 0, len(zones))
+	carbonEstimator.Status.Zones = make([]sustainkubecomv1alpha1.ZoneEmission, 0, len(zones))
+	for _, z := range zones {
+		fetched := sampleTime
+		if z.intensity.Source == utils.IntensitySourceLastKnownGood {
+			fetched = z.intensity.Timestamp
+		}
+		samples = append(samples, metrics.Zone{
+			Zone:     z.zone,
+			Source:   z.intensity.Source,
+			Power:    z.watts,
+			Emission: z.emissionRate(),
+		})
+		carbonEstimator.Status.Zones = append(carbonEstimator.Status.Zones, sustainkubecomv1alpha1.ZoneEmission{
+			Zone:            z.zone,
+			Nodes:           int32(z.nodes),
+			CarbonIntensity: strconv.FormatFloat(z.intensity.Value, 'f', 2, 64),
+			Power:           strconv.FormatFloat(z.watts, 'f', 2, 64),
+			Emission:        strconv.FormatFloat(z.emissionRate(), 'f', 2, 64),
+			IntensitySource: z.intensity.Source,
+			IntensityTime:   &metav1.Time{Time: fetched},
+		})
+	}
+	return samples
+}
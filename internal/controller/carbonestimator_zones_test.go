@@ -0,0 +1,102 @@
+//go:build unit
+// +build unit
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sustainkubecomv1alpha1 "sustain_kube/api/v1alpha1"
+	"sustain_kube/internal/controller/power"
+	"sustain_kube/internal/controller/provider"
+)
+
+func regionNode(name, region string) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+	if region != "" {
+		node.Labels["topology.kubernetes.io/region"] = region
+	}
+	return node
+}
+
+func TestZoneEmissions_SplitsNodesByRegion(t *testing.T) {
+	intensities := map[string]int{"DE": 400, "FR": 50}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zone := r.URL.Query().Get("zone")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"zone":%q,"carbonIntensity":%d}`, zone, intensities[zone])
+	}))
+	defer ts.Close()
+
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().WithObjects(
+		regionNode("de-1", "eu-central-1"),
+		regionNode("fr-1", "eu-west-3"),
+		regionNode("fr-2", "eu-west-3"),
+		regionNode("edge", ""),
+	).Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		ObjectMeta: metav1.ObjectMeta{Name: "ce", Namespace: "default"},
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{
+			Zone: "DE",
+			Provider: &sustainkubecomv1alpha1.ProviderSpec{
+				Name: provider.ElectricityMaps, Options: map[string]string{"url": ts.URL},
+			},
+			Zones: &sustainkubecomv1alpha1.ZoneMappingSpec{ByRegion: map[string]string{
+				"eu-central-1": "DE",
+				"eu-west-3":    "FR",
+			}},
+		},
+	}
+	facility := power.NewMeasurement(map[string]float64{"de-1": 100, "fr-1": 200, "fr-2": 300, "edge": 50})
+
+	zones, err := r.zoneEmissions(context.Background(), ce, nil, facility, "DE",
+		provider.CarbonIntensity{Value: 400, Source: provider.ElectricityMaps})
+	if err != nil {
+		t.Fatalf("zoneEmissions failed: %v", err)
+	}
+	if len(zones) != 2 {
+		t.Fatalf("expected two zones, got %+v", zones)
+	}
+
+	// nodes without a mapped region belong to the zone of the estimator
+	de, fr := zones[0], zones[1]
+	if de.zone != "DE" || de.nodes != 2 || de.watts != 150 || de.emissionRate() != 60 {
+		t.Fatalf("unexpected DE subtotal: %+v", de)
+	}
+	if fr.zone != "FR" || fr.nodes != 2 || fr.watts != 500 || fr.emissionRate() != 25 {
+		t.Fatalf("unexpected FR subtotal: %+v", fr)
+	}
+
+	// the breakdown is kept in status
+	r.updateZones(ce, zones, metav1.Now().Time, "DE")
+	if len(ce.Status.Zones) != 2 || ce.Status.Zones[1].CarbonIntensity != "50.00" || ce.Status.Zones[1].Emission != "25.00" {
+		t.Fatalf("unexpected zones in status: %+v", ce.Status.Zones)
+	}
+}
+
+func TestZoneEmissions_RequiresNodePower(t *testing.T) {
+	r := &CarbonEstimatorReconciler{Client: fake.NewClientBuilder().Build()}
+	ce := &sustainkubecomv1alpha1.CarbonEstimator{
+		Spec: sustainkubecomv1alpha1.CarbonEstimatorSpec{Zones: &sustainkubecomv1alpha1.ZoneMappingSpec{}},
+	}
+
+	if _, err := r.zoneEmissions(context.Background(), ce, nil, power.Measurement{Total: 100}, "TW",
+		provider.CarbonIntensity{Value: 500}); err == nil {
+		t.Fatalf("expected an error without a per-node power breakdown")
+	}
+
+	ce.Spec.Zones = nil
+	zones, err := r.zoneEmissions(context.Background(), ce, nil, power.Measurement{Total: 100}, "TW",
+		provider.CarbonIntensity{Value: 500})
+	if err != nil || len(zones) != 1 || zones[0].emissionRate() != 50 {
+		t.Fatalf("unexpected single zone: %+v %v", zones, err)
+	}
+}
@@ -0,0 +1,30 @@
+package embodied
+
+// hoursPerYear is the average number of hours in a year, leap years included.
+const hoursPerYear = 365.25 * 24
+
+// Node is the manufacturing footprint of a node.
+type Node struct {
+	// KgCO2e is the embodied emissions of the node in kgCO2e
+	KgCO2e float64
+	// LifespanYears is the lifespan the embodied emissions are amortized over
+	LifespanYears float64
+}
+
+// Rate amortizes the embodied emissions of the node linearly over its lifespan and returns them
+// as an emission rate in gCO2eq/h. A node without a lifespan has no embodied emission rate.
+func (n Node) Rate() float64 {
+	if n.LifespanYears <= 0 {
+		return 0
+	}
+	return n.KgCO2e * 1000 / (n.LifespanYears * hoursPerYear)
+}
+
+// Rate returns the total embodied emission rate of the nodes in gCO2eq/h.
+func Rate(nodes []Node) float64 {
+	var total float64
+	for _, node := range nodes {
+		total += node.Rate()
+	}
+	return total
+}
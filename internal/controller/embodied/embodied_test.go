@@ -0,0 +1,32 @@
+//go:build unit
+// +build unit
+
+package embodied
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNodeRate(t *testing.T) {
+	// 1200 kgCO2e over 4 years
+	node := Node{KgCO2e: 1200, LifespanYears: 4}
+	want := 1200.0 * 1000 / (4 * 365.25 * 24)
+	if got := node.Rate(); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("unexpected rate: got %v want %v", got, want)
+	}
+
+	if got := (Node{KgCO2e: 1200}).Rate(); got != 0 {
+		t.Fatalf("expected no rate without a lifespan, got %v", got)
+	}
+}
+
+func TestRate(t *testing.T) {
+	worker := Node{KgCO2e: 200, LifespanYears: 4}
+	total := Rate([]Node{worker, {KgCO2e: 100, LifespanYears: 2}, {KgCO2e: 500}})
+
+	// both nodes emit the same rate, the node without a lifespan is not amortized
+	if math.Abs(total-2*worker.Rate()) > 1e-9 {
+		t.Fatalf("unexpected total: got %v want %v", total, 2*worker.Rate())
+	}
+}
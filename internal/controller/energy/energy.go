@@ -0,0 +1,43 @@
+package energy
+
+import (
+	"sort"
+	"time"
+)
+
+// Sample is a power reading at a point in time.
+type Sample struct {
+	Time  time.Time
+	Watts float64
+}
+
+// IntegrateKWh integrates the power samples over time with the trapezoidal rule and
+// returns the consumed energy in kWh. Samples do not need to be sorted; fewer than
+// two samples yield no energy.
+func IntegrateKWh(samples []Sample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	var wattHours float64
+	for i := 1; i < len(sorted); i++ {
+		hours := sorted[i].Time.Sub(sorted[i-1].Time).Hours()
+		wattHours += (sorted[i].Watts + sorted[i-1].Watts) / 2 * hours
+	}
+
+	return wattHours / 1000
+}
+
+// EmissionsGrams converts energy in kWh to emissions in gCO2eq using a carbon intensity in gCO2eq/kWh.
+func EmissionsGrams(kWh, intensity float64) float64 {
+	return kWh * intensity
+}
+
+// EmissionRate converts power in Watts to an emission rate in gCO2eq/h using a carbon intensity in gCO2eq/kWh.
+func EmissionRate(watts, intensity float64) float64 {
+	return watts / 1000 * intensity
+}
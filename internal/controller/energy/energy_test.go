@@ -0,0 +1,40 @@
+//go:build unit
+// +build unit
+
+package energy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIntegrateKWh_Trapezoid(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		// unsorted on purpose
+		{Time: start.Add(time.Hour), Watts: 200},
+		{Time: start, Watts: 100},
+		{Time: start.Add(2 * time.Hour), Watts: 200},
+	}
+
+	// (100+200)/2 * 1h + (200+200)/2 * 1h = 350 Wh
+	if got := IntegrateKWh(samples); math.Abs(got-0.35) > 1e-9 {
+		t.Fatalf("unexpected energy: got %v want %v", got, 0.35)
+	}
+}
+
+func TestIntegrateKWh_NotEnoughSamples(t *testing.T) {
+	if got := IntegrateKWh([]Sample{{Time: time.Now(), Watts: 100}}); got != 0 {
+		t.Fatalf("expected no energy for a single sample, got %v", got)
+	}
+}
+
+func TestEmissions(t *testing.T) {
+	if got := EmissionsGrams(0.35, 300); math.Abs(got-105) > 1e-9 {
+		t.Fatalf("unexpected emissions: got %v want %v", got, 105.0)
+	}
+	if got := EmissionRate(100.5, 300.5); math.Abs(got-30.20025) > 1e-9 {
+		t.Fatalf("unexpected emission rate: got %v want %v", got, 30.20025)
+	}
+}
@@ -0,0 +1,134 @@
+//go:build unit
+// +build unit
+
+package forecast
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// daily returns hourly samples over the given days, following the same profile every day.
+func daily(start time.Time, days int, profile func(hour int) float64) []Sample {
+	series := make([]Sample, 0, days*Season)
+	for i := 0; i < days*Season; i++ {
+		series = append(series, Sample{Time: start.Add(time.Duration(i) * time.Hour), Value: profile(i % Season)})
+	}
+	return series
+}
+
+func TestHistory_HourlyMeans(t *testing.T) {
+	h := NewHistory()
+	hour := time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC)
+	h.Add("DE", hour.Add(5*time.Minute), 100)
+	h.Add("DE", hour.Add(35*time.Minute), 200)
+	h.Add("DE", hour.Add(time.Hour), 50)
+	h.Add("FR", hour, 30)
+
+	if mean, ok := h.Mean("DE", hour); !ok || mean != 150 {
+		t.Fatalf("unexpected mean: %v %v", mean, ok)
+	}
+	series := h.Series("DE", hour.Add(time.Hour))
+	if len(series) != 1 || series[0].Value != 50 {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+
+	// readings older than the history are dropped
+	h.Add("DE", hour.Add(MaxHistory+2*time.Hour), 80)
+	if _, ok := h.Mean("DE", hour); ok {
+		t.Fatalf("expected the oldest hour to be dropped")
+	}
+
+	var nilHistory *History
+	nilHistory.Add("DE", hour, 1)
+	if series := nilHistory.Series("DE", hour); series != nil {
+		t.Fatalf("unexpected series of a nil history: %+v", series)
+	}
+}
+
+func TestSeasonalNaive(t *testing.T) {
+	start := time.Date(2025, 5, 19, 0, 0, 0, 0, time.UTC)
+	series := daily(start, 2, func(hour int) float64 { return float64(100 + hour) })
+	series[Season+3].Value = 203 // the second day differs at 03:00
+
+	forecast, err := SeasonalNaive(series, start.Add(2*Season*time.Hour), 5)
+	if err != nil {
+		t.Fatalf("SeasonalNaive failed: %v", err)
+	}
+	want := []float64{100, 101, 102, 153, 104}
+	for i, sample := range forecast {
+		if sample.Value != want[i] {
+			t.Fatalf("unexpected value of hour %d: got %v want %v", i, sample.Value, want[i])
+		}
+	}
+
+	// hours without a past day fall back to the latest sample
+	forecast, _ = SeasonalNaive(series[len(series)-2:], start.Add(2*Season*time.Hour), 1)
+	if forecast[0].Value != 123 {
+		t.Fatalf("unexpected fallback: %+v", forecast)
+	}
+
+	if _, err := SeasonalNaive(nil, start, 1); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("expected ErrNoHistory, got %v", err)
+	}
+}
+
+func TestHoltWinters(t *testing.T) {
+	start := time.Date(2025, 5, 14, 0, 0, 0, 0, time.UTC)
+	profile := func(hour int) float64 { return 300 + 100*math.Sin(2*math.Pi*float64(hour)/Season) }
+	series := daily(start, 7, profile)
+
+	forecast, err := HoltWinters(series, start.Add(7*Season*time.Hour), Season)
+	if err != nil {
+		t.Fatalf("HoltWinters failed: %v", err)
+	}
+	if len(forecast) != Season {
+		t.Fatalf("unexpected forecast length: %d", len(forecast))
+	}
+	for i, sample := range forecast {
+		if want := profile(i); math.Abs(sample.Value-want) > 5 {
+			t.Fatalf("unexpected value of hour %d: got %v want about %v", i, sample.Value, want)
+		}
+	}
+
+	// hours already in the history are returned as observed
+	last := series[len(series)-1]
+	forecast, _ = HoltWinters(series, last.Time, 2)
+	if forecast[0].Value != last.Value {
+		t.Fatalf("unexpected value of an observed hour: %+v", forecast[0])
+	}
+
+	if _, err := HoltWinters(series[:Season], start.Add(Season*time.Hour), 1); err == nil {
+		t.Fatalf("expected an error with a single day of history")
+	}
+}
+
+func TestTracker_Evaluate(t *testing.T) {
+	tracker := NewTracker()
+	now := time.Date(2025, 5, 21, 10, 30, 0, 0, time.UTC)
+	hour := now.Truncate(time.Hour)
+	tracker.Record("default/ce", []Sample{
+		{Time: hour, Value: 999}, // already started, not tracked
+		{Time: hour.Add(time.Hour), Value: 110},
+		{Time: hour.Add(2 * time.Hour), Value: 300},
+	}, now)
+
+	if _, _, ok := tracker.Evaluate("default/ce", hour, 100); ok {
+		t.Fatalf("expected the started hour not to be evaluated")
+	}
+	ape, mape, ok := tracker.Evaluate("default/ce", hour.Add(time.Hour), 100)
+	if !ok || math.Abs(ape-0.1) > 1e-9 || math.Abs(mape-0.1) > 1e-9 {
+		t.Fatalf("unexpected error: %v %v %v", ape, mape, ok)
+	}
+	ape, mape, _ = tracker.Evaluate("default/ce", hour.Add(2*time.Hour), 200)
+	if math.Abs(ape-0.5) > 1e-9 || math.Abs(mape-0.3) > 1e-9 {
+		t.Fatalf("unexpected error: %v %v", ape, mape)
+	}
+
+	tracker.Forget("default/ce")
+	if _, _, ok := tracker.Evaluate("default/ce", hour.Add(2*time.Hour), 200); ok {
+		t.Fatalf("expected a forgotten estimator not to be evaluated")
+	}
+}
@@ -0,0 +1,101 @@
+package forecast
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxHistory is how long the intensity history of a zone is kept.
+const MaxHistory = 28 * 24 * time.Hour
+
+// Sample is the mean carbon intensity of the hour starting at Time, in gCO2eq/kWh.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// History collects the carbon intensity readings of every zone as hourly means, shared by all
+// estimators of the zone. It is kept in memory, the built-in forecast warms up again after a restart.
+//
+// A nil *History records nothing.
+type History struct {
+	mu    sync.Mutex
+	zones map[string]map[time.Time]*hourlyMean
+}
+
+type hourlyMean struct {
+	sum   float64
+	count int
+}
+
+// NewHistory builds an empty history.
+func NewHistory() *History {
+	return &History{zones: map[string]map[time.Time]*hourlyMean{}}
+}
+
+// Add records a reading of the zone taken at t. Readings older than MaxHistory are dropped.
+func (h *History) Add(zone string, t time.Time, value float64) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hours, ok := h.zones[zone]
+	if !ok {
+		hours = map[time.Time]*hourlyMean{}
+		h.zones[zone] = hours
+	}
+
+	hour := t.Truncate(time.Hour)
+	mean, ok := hours[hour]
+	if !ok {
+		mean = &hourlyMean{}
+		hours[hour] = mean
+	}
+	mean.sum += value
+	mean.count++
+
+	for start := range hours {
+		if t.Sub(start) > MaxHistory {
+			delete(hours, start)
+		}
+	}
+}
+
+// Series returns the hourly means of the zone since the given time, sorted by time.
+func (h *History) Series(zone string, since time.Time) []Sample {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var series []Sample
+	for start, mean := range h.zones[zone] {
+		if !start.Before(since) {
+			series = append(series, Sample{Time: start, Value: mean.sum / float64(mean.count)})
+		}
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Time.Before(series[j].Time) })
+	return series
+}
+
+// Mean returns the mean intensity of the zone over the hour starting at hour, if any reading was recorded.
+func (h *History) Mean(zone string, hour time.Time) (float64, bool) {
+	if h == nil {
+		return 0, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	mean, ok := h.zones[zone][hour]
+	if !ok {
+		return 0, false
+	}
+	return mean.sum / float64(mean.count), true
+}
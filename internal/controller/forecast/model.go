@@ -0,0 +1,125 @@
+package forecast
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Season is the length of the daily cycle of the carbon intensity, in hourly samples.
+const Season = 24
+
+// Smoothing factors of the Holt-Winters model for the level, the trend and the seasonal component.
+// The trend adapts slowly, grid intensity follows a strong daily cycle but drifts little over days.
+const (
+	alpha = 0.3
+	beta  = 0.05
+	gamma = 0.2
+)
+
+// ErrNoHistory is returned when the history holds no sample to forecast from.
+var ErrNoHistory = errors.New("no carbon intensity history")
+
+// SeasonalNaive forecasts every hour from start on as the mean of the same hour of the previous days
+// in the history, or the latest sample for hours without any. series must be hourly and sorted.
+func SeasonalNaive(series []Sample, start time.Time, hours int) ([]Sample, error) {
+	if len(series) == 0 {
+		return nil, ErrNoHistory
+	}
+
+	values := make(map[time.Time]float64, len(series))
+	for _, sample := range series {
+		values[sample.Time] = sample.Value
+	}
+	first, latest := series[0].Time, series[len(series)-1].Value
+
+	forecast := make([]Sample, 0, hours)
+	for i := 0; i < hours; i++ {
+		t := start.Add(time.Duration(i) * time.Hour)
+
+		var sum float64
+		var count int
+		for past := t.Add(-Season * time.Hour); !past.Before(first); past = past.Add(-Season * time.Hour) {
+			if value, ok := values[past]; ok {
+				sum += value
+				count++
+			}
+		}
+
+		value := latest
+		if count > 0 {
+			value = sum / float64(count)
+		}
+		forecast = append(forecast, Sample{Time: t, Value: value})
+	}
+	return forecast, nil
+}
+
+// HoltWinters forecasts every hour from start on with additive Holt-Winters exponential smoothing
+// (level, trend and daily seasonality) fitted on the history. It needs two days of history, missing
+// hours are filled with the previous sample. series must be hourly and sorted.
+func HoltWinters(series []Sample, start time.Time, hours int) ([]Sample, error) {
+	if len(series) == 0 {
+		return nil, ErrNoHistory
+	}
+
+	// contiguous hourly values from the first sample to the last
+	first, last := series[0].Time, series[len(series)-1].Time
+	x := make([]float64, 0, int(last.Sub(first).Hours())+1)
+	next := 0
+	for t := first; !t.After(last); t = t.Add(time.Hour) {
+		if next < len(series) && series[next].Time.Equal(t) {
+			x = append(x, series[next].Value)
+			next++
+		} else {
+			x = append(x, x[len(x)-1])
+		}
+	}
+	if len(x) < 2*Season {
+		return nil, fmt.Errorf("holt-winters needs %d hours of carbon intensity history, got %d", 2*Season, len(x))
+	}
+
+	// initial level, trend and seasonal components from the first two seasons
+	firstMean, secondMean := mean(x[:Season]), mean(x[Season:2*Season])
+	level := firstMean
+	trend := (secondMean - firstMean) / Season
+	seasonal := make([]float64, len(x))
+	for i := 0; i < Season; i++ {
+		seasonal[i] = x[i] - firstMean
+	}
+
+	for t := Season; t < len(x); t++ {
+		previousLevel := level
+		level = alpha*(x[t]-seasonal[t-Season]) + (1-alpha)*(level+trend)
+		trend = beta*(level-previousLevel) + (1-beta)*trend
+		seasonal[t] = gamma*(x[t]-level) + (1-gamma)*seasonal[t-Season]
+	}
+
+	n := len(x)
+	forecast := make([]Sample, 0, hours)
+	for i := 0; i < hours; i++ {
+		t := start.Add(time.Duration(i) * time.Hour)
+		h := int(math.Round(t.Sub(last).Hours()))
+		if h < 1 {
+			// hours already in the history are not forecast
+			if index := n - 1 + h; index >= 0 {
+				forecast = append(forecast, Sample{Time: t, Value: x[index]})
+			}
+			continue
+		}
+
+		// the latest fitted seasonal component of the same hour of the day
+		s := seasonal[n-1+h-Season*((h-1)/Season+1)]
+		forecast = append(forecast, Sample{Time: t, Value: math.Max(level+float64(h)*trend+s, 0)})
+	}
+	return forecast, nil
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,103 @@
+package forecast
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// errorWindow is the period the mean absolute percentage error is computed over.
+const errorWindow = 24 * time.Hour
+
+// Tracker evaluates the forecasts of every estimator against the actual intensity. For every hour,
+// it keeps the latest forecast issued before the hour started, i.e. at least one hour ahead.
+//
+// A nil *Tracker evaluates nothing.
+type Tracker struct {
+	mu         sync.Mutex
+	estimators map[string]*tracked
+}
+
+type tracked struct {
+	// forecasts by hour
+	forecasts map[time.Time]float64
+	// absolute percentage errors by hour
+	errors map[time.Time]float64
+}
+
+// NewTracker builds an empty tracker.
+func NewTracker() *Tracker {
+	return &Tracker{estimators: map[string]*tracked{}}
+}
+
+// Record keeps the forecast of the hours starting after now, replacing earlier forecasts of these hours.
+func (t *Tracker) Record(key string, forecast []Sample, now time.Time) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	estimator, ok := t.estimators[key]
+	if !ok {
+		estimator = &tracked{forecasts: map[time.Time]float64{}, errors: map[time.Time]float64{}}
+		t.estimators[key] = estimator
+	}
+
+	for _, sample := range forecast {
+		if sample.Time.After(now) {
+			estimator.forecasts[sample.Time] = sample.Value
+		}
+	}
+	for hour := range estimator.forecasts {
+		if now.Sub(hour) > errorWindow {
+			delete(estimator.forecasts, hour)
+		}
+	}
+}
+
+// Evaluate compares the forecast of the hour with its actual mean intensity. It returns the absolute
+// percentage error of the hour, as a ratio, and the mean over the hours evaluated in the last day.
+// ok is false when the hour was not forecast or its actual intensity is 0.
+func (t *Tracker) Evaluate(key string, hour time.Time, actual float64) (ape, mape float64, ok bool) {
+	if t == nil || actual == 0 {
+		return 0, 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	estimator, found := t.estimators[key]
+	if !found {
+		return 0, 0, false
+	}
+	forecast, found := estimator.forecasts[hour]
+	if !found {
+		return 0, 0, false
+	}
+
+	ape = math.Abs(forecast-actual) / actual
+	estimator.errors[hour] = ape
+
+	var sum float64
+	for evaluated, e := range estimator.errors {
+		if hour.Sub(evaluated) >= errorWindow {
+			delete(estimator.errors, evaluated)
+			continue
+		}
+		sum += e
+	}
+	return ape, sum / float64(len(estimator.errors)), true
+}
+
+// Forget drops the forecasts and errors of an estimator.
+func (t *Tracker) Forget(key string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.estimators, key)
+}
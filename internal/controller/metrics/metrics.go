@@ -1,6 +1,10 @@
 package metrics
 
 import (
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -8,9 +12,51 @@ import (
 
 type Metrics struct {
 	PowerConsumption *prometheus.GaugeVec
+	FacilityPower    *prometheus.GaugeVec
 	CarbonEmission   *prometheus.GaugeVec
+	EmbodiedEmission *prometheus.GaugeVec
+	Forecast         *prometheus.GaugeVec
+	ForecastError    *prometheus.GaugeVec
 	WarningLevel     *prometheus.GaugeVec
 	CriticalLevel    *prometheus.GaugeVec
+
+	EnergyTotal   *prometheus.CounterVec
+	EmissionTotal *prometheus.CounterVec
+
+	ReconcileFailures *prometheus.CounterVec
+
+	NamespacePower    *prometheus.GaugeVec
+	NamespaceEmission *prometheus.GaugeVec
+	WorkloadPower     *prometheus.GaugeVec
+	WorkloadEmission  *prometheus.GaugeVec
+
+	// process-wide carbon intensity cache and provider API metrics
+	IntensityCacheRequests *prometheus.CounterVec
+	ProviderRequestLatency *prometheus.HistogramVec
+	ProviderErrors         *prometheus.CounterVec
+	ProviderQuotaRemaining *prometheus.GaugeVec
+}
+
+// Workload is the power and emission rate attributed to a workload.
+type Workload struct {
+	Kind      string
+	Name      string
+	Namespace string
+	// Power in W
+	Power float64
+	// Emission rate in gCO2eq/h
+	Emission float64
+}
+
+// Zone is the facility power and emission rate of the nodes of an estimator in a grid zone.
+type Zone struct {
+	Zone string
+	// Source of the carbon intensity of the zone
+	Source string
+	// Power in W
+	Power float64
+	// Emission rate in gCO2eq/h
+	Emission float64
 }
 
 func SetupMetrics(prefix string) Metrics {
@@ -19,22 +65,102 @@ func SetupMetrics(prefix string) Metrics {
 			Namespace: prefix,
 			Name:      "carbon_estimator_power_consumption",
 			Help:      "Power consumption of the CarbonEstimator resource in Watts",
-		}, []string{"name", "namespace"}),
+		}, []string{"name", "namespace", "zone"}),
+		FacilityPower: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_facility_power_watts",
+			Help:      "Power consumption of the CarbonEstimator resource including datacenter overhead in Watts",
+		}, []string{"name", "namespace", "zone"}),
 		CarbonEmission: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: prefix,
 			Name:      "carbon_estimator_carbon_emission",
-			Help:      "Carbon emission of the CarbonEstimator resource in CO2e",
-		}, []string{"name", "namespace"}),
+			Help:      "Carbon emission rate of the CarbonEstimator resource in gCO2eq/h",
+		}, []string{"name", "namespace", "zone", "source"}),
+		EmbodiedEmission: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_embodied_emission",
+			Help:      "Embodied carbon emission rate of the nodes of the CarbonEstimator resource in gCO2eq/h",
+		}, []string{"name", "namespace", "zone"}),
+		// horizon is the number of hours between the current hour and the forecast hour, e.g. 0h, 1h
+		Forecast: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_intensity_forecast",
+			Help:      "Forecast carbon intensity of the zone of the CarbonEstimator resource in gCO2eq/kWh",
+		}, []string{"name", "namespace", "zone", "horizon"}),
+		ForecastError: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_intensity_forecast_error",
+			Help:      "Absolute percentage error, as a ratio, of the forecast of the last complete hour against its actual carbon intensity",
+		}, []string{"name", "namespace", "zone", "source"}),
 		WarningLevel: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: prefix,
 			Name:      "carbon_estimator_warning_level",
 			Help:      "Info about CarbonEstimator resource",
-		}, []string{"name", "namespace"}),
+		}, []string{"name", "namespace", "zone"}),
 		CriticalLevel: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: prefix,
 			Name:      "carbon_estimator_critical_level",
 			Help:      "Info about CarbonEstimator resource",
-		}, []string{"name", "namespace"}),
+		}, []string{"name", "namespace", "zone"}),
+		EnergyTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_energy_kwh_total",
+			Help:      "Energy consumed by the CarbonEstimator resource in kWh",
+		}, []string{"name", "namespace", "zone"}),
+		EmissionTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_emissions_grams_total",
+			Help:      "Carbon emissions of the CarbonEstimator resource in gCO2eq",
+		}, []string{"name", "namespace", "zone"}),
+		// reason is the reason of the Ready condition of the failed reconcile, e.g. PrometheusUnreachable
+		ReconcileFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_reconcile_failures_total",
+			Help:      "Failed reconciles of the CarbonEstimator resource by reason",
+		}, []string{"name", "namespace", "reason"}),
+		// attributed series describe the namespace the power is attributed to,
+		// the estimator is identified by the estimator and estimator_namespace labels
+		NamespacePower: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_namespace_power_watts",
+			Help:      "Power consumption attributed to a namespace in Watts",
+		}, []string{"estimator", "estimator_namespace", "namespace", "zone"}),
+		NamespaceEmission: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_namespace_emission",
+			Help:      "Carbon emission rate attributed to a namespace in gCO2eq/h",
+		}, []string{"estimator", "estimator_namespace", "namespace", "zone"}),
+		WorkloadPower: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_workload_power_watts",
+			Help:      "Power consumption attributed to a workload in Watts",
+		}, []string{"estimator", "estimator_namespace", "kind", "name", "namespace", "zone"}),
+		WorkloadEmission: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_estimator_workload_emission",
+			Help:      "Carbon emission rate attributed to a workload in gCO2eq/h",
+		}, []string{"estimator", "estimator_namespace", "kind", "name", "namespace", "zone"}),
+		IntensityCacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "carbon_intensity_cache_requests_total",
+			Help:      "Carbon intensity cache lookups by provider and result (hit or miss)",
+		}, []string{"provider", "result"}),
+		ProviderRequestLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prefix,
+			Name:      "carbon_intensity_api_request_duration_seconds",
+			Help:      "Latency of the carbon intensity provider API requests in seconds",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		ProviderErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "carbon_intensity_api_errors_total",
+			Help:      "Failed carbon intensity provider API requests",
+		}, []string{"provider"}),
+		ProviderQuotaRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "carbon_intensity_api_quota_remaining",
+			Help:      "Requests left in the current quota period of the carbon intensity provider",
+		}, []string{"provider"}),
 	}
 	return carbonEstimatorMetrics
 }
@@ -42,53 +168,315 @@ func SetupMetrics(prefix string) Metrics {
 func (m Metrics) MustRegister(registry metrics.RegistererGatherer) Metrics {
 	registry.MustRegister(
 		m.PowerConsumption,
+		m.FacilityPower,
 		m.CarbonEmission,
+		m.EmbodiedEmission,
+		m.Forecast,
+		m.ForecastError,
 		m.WarningLevel,
 		m.CriticalLevel,
+		m.EnergyTotal,
+		m.EmissionTotal,
+		m.ReconcileFailures,
+		m.NamespacePower,
+		m.NamespaceEmission,
+		m.WorkloadPower,
+		m.WorkloadEmission,
+		m.IntensityCacheRequests,
+		m.ProviderRequestLatency,
+		m.ProviderErrors,
+		m.ProviderQuotaRemaining,
 	)
 	return m
 }
 
-func (m *Metrics) Update(consumption, emission float64, warningLevel, criticalLevel uint, req ctrl.Request) {
+// Update sets the gauges of the estimator. source is the carbon intensity source the emission rate is computed with.
+func (m *Metrics) Update(consumption, emission float64, warningLevel, criticalLevel uint, zone, source string, req ctrl.Request) {
+	// the zone of an estimator can change, drop the series of the previous zone first
+	m.deleteGauges(req)
+
 	m.PowerConsumption.With(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
+		"zone":      zone,
 	}).Set(consumption)
 
 	m.CarbonEmission.With(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
+		"zone":      zone,
+		"source":    source,
 	}).Set(emission)
 
 	m.WarningLevel.With(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
+		"zone":      zone,
 	}).Set(float64(warningLevel))
 
 	m.CriticalLevel.With(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
+		"zone":      zone,
 	}).Set(float64(criticalLevel))
 }
 
+// UpdateFacility sets the facility power (W) of the estimator. Update must be called first.
+func (m *Metrics) UpdateFacility(facilityConsumption float64, zone string, req ctrl.Request) {
+	m.FacilityPower.With(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"zone":      zone,
+	}).Set(facilityConsumption)
+}
+
+// UpdateZones replaces the facility power and emission rate of the estimator by one series per grid zone,
+// for estimators spanning several zones. Update and UpdateFacility must be called first.
+func (m *Metrics) UpdateZones(zones []Zone, req ctrl.Request) {
+	labels := prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	}
+	m.FacilityPower.DeletePartialMatch(labels)
+	m.CarbonEmission.DeletePartialMatch(labels)
+
+	for _, zone := range zones {
+		m.FacilityPower.With(prometheus.Labels{
+			"name":      req.Name,
+			"namespace": req.Namespace,
+			"zone":      zone.Zone,
+		}).Set(zone.Power)
+
+		m.CarbonEmission.With(prometheus.Labels{
+			"name":      req.Name,
+			"namespace": req.Namespace,
+			"zone":      zone.Zone,
+			"source":    zone.Source,
+		}).Set(zone.Emission)
+	}
+}
+
+// UpdateEmbodied sets the embodied emission rate (gCO2eq/h) of the estimator. Update must be called first.
+func (m *Metrics) UpdateEmbodied(embodiedEmission float64, zone string, req ctrl.Request) {
+	m.EmbodiedEmission.With(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"zone":      zone,
+	}).Set(embodiedEmission)
+}
+
+// UpdateForecast replaces the forecast carbon intensity (gCO2eq/kWh) of the estimator, keyed by the start of
+// the forecast hours. The current hour has the 0h horizon, past hours are skipped.
+func (m *Metrics) UpdateForecast(forecast map[time.Time]float64, now time.Time, zone string, req ctrl.Request) {
+	m.Forecast.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+
+	current := now.Truncate(time.Hour)
+	for start, value := range forecast {
+		hours := int(math.Round(start.Sub(current).Hours()))
+		if hours < 0 {
+			continue
+		}
+		m.Forecast.With(prometheus.Labels{
+			"name":      req.Name,
+			"namespace": req.Namespace,
+			"zone":      zone,
+			"horizon":   fmt.Sprintf("%dh", hours),
+		}).Set(value)
+	}
+}
+
+// UpdateForecastError sets the absolute percentage error of the forecast of the estimator from the given source.
+func (m *Metrics) UpdateForecastError(ape float64, zone, source string, req ctrl.Request) {
+	m.ForecastError.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+
+	m.ForecastError.With(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"zone":      zone,
+		"source":    source,
+	}).Set(ape)
+}
+
+// AddEnergy increases the energy (kWh) and emission (gCO2eq) counters by the amounts of the last interval.
+func (m *Metrics) AddEnergy(energyKWh, emissionGrams float64, zone string, req ctrl.Request) {
+	m.EnergyTotal.With(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"zone":      zone,
+	}).Add(energyKWh)
+
+	m.EmissionTotal.With(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"zone":      zone,
+	}).Add(emissionGrams)
+}
+
+// ObserveFailure counts a failed reconcile of the estimator.
+func (m *Metrics) ObserveFailure(reason string, req ctrl.Request) {
+	m.ReconcileFailures.With(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"reason":    reason,
+	}).Inc()
+}
+
+// UpdateNamespaces replaces the attributed power (W) and emission rate (gCO2eq/h) of every namespace.
+func (m *Metrics) UpdateNamespaces(power, emission map[string]float64, zone string, req ctrl.Request) {
+	m.deleteNamespaces(req)
+
+	for namespace, watts := range power {
+		m.NamespacePower.With(prometheus.Labels{
+			"estimator":           req.Name,
+			"estimator_namespace": req.Namespace,
+			"namespace":           namespace,
+			"zone":                zone,
+		}).Set(watts)
+	}
+
+	for namespace, rate := range emission {
+		m.NamespaceEmission.With(prometheus.Labels{
+			"estimator":           req.Name,
+			"estimator_namespace": req.Namespace,
+			"namespace":           namespace,
+			"zone":                zone,
+		}).Set(rate)
+	}
+}
+
+// UpdateWorkloads replaces the attributed power and emission rate of every workload.
+func (m *Metrics) UpdateWorkloads(workloads []Workload, zone string, req ctrl.Request) {
+	m.deleteWorkloads(req)
+
+	for _, workload := range workloads {
+		labels := prometheus.Labels{
+			"estimator":           req.Name,
+			"estimator_namespace": req.Namespace,
+			"kind":                workload.Kind,
+			"name":                workload.Name,
+			"namespace":           workload.Namespace,
+			"zone":                zone,
+		}
+		m.WorkloadPower.With(labels).Set(workload.Power)
+		m.WorkloadEmission.With(labels).Set(workload.Emission)
+	}
+}
+
+// ObserveCache counts a carbon intensity cache lookup.
+func (m *Metrics) ObserveCache(provider string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.IntensityCacheRequests.WithLabelValues(provider, result).Inc()
+}
+
+// ObserveRequest records the latency of a provider API request and counts it if it failed.
+func (m *Metrics) ObserveRequest(provider string, duration time.Duration, err error) {
+	m.ProviderRequestLatency.WithLabelValues(provider).Observe(duration.Seconds())
+	if err != nil {
+		m.ProviderErrors.WithLabelValues(provider).Inc()
+	}
+}
+
+// ObserveQuota sets the requests left in the current quota period of a provider.
+func (m *Metrics) ObserveQuota(provider string, remaining int) {
+	m.ProviderQuotaRemaining.WithLabelValues(provider).Set(float64(remaining))
+}
+
 func (m *Metrics) Delete(req ctrl.Request) {
-	m.PowerConsumption.Delete(prometheus.Labels{
+	m.deleteGauges(req)
+	m.DeleteForecast(req)
+	m.deleteNamespaces(req)
+	m.deleteWorkloads(req)
+
+	m.EnergyTotal.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+
+	m.EmissionTotal.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+
+	m.ReconcileFailures.DeletePartialMatch(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
 	})
+}
 
-	m.CarbonEmission.Delete(prometheus.Labels{
+func (m *Metrics) deleteGauges(req ctrl.Request) {
+	m.PowerConsumption.DeletePartialMatch(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
 	})
 
-	m.WarningLevel.Delete(prometheus.Labels{
+	m.FacilityPower.DeletePartialMatch(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
 	})
 
-	m.CriticalLevel.Delete(prometheus.Labels{
+	m.CarbonEmission.DeletePartialMatch(prometheus.Labels{
 		"name":      req.Name,
 		"namespace": req.Namespace,
 	})
+
+	m.EmbodiedEmission.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+
+	m.WarningLevel.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+
+	m.CriticalLevel.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+}
+
+// DeleteForecast removes the forecast and forecast error series of the estimator.
+func (m *Metrics) DeleteForecast(req ctrl.Request) {
+	m.Forecast.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+
+	m.ForecastError.DeletePartialMatch(prometheus.Labels{
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	})
+}
+
+func (m *Metrics) deleteNamespaces(req ctrl.Request) {
+	m.NamespacePower.DeletePartialMatch(prometheus.Labels{
+		"estimator":           req.Name,
+		"estimator_namespace": req.Namespace,
+	})
+
+	m.NamespaceEmission.DeletePartialMatch(prometheus.Labels{
+		"estimator":           req.Name,
+		"estimator_namespace": req.Namespace,
+	})
+}
+
+func (m *Metrics) deleteWorkloads(req ctrl.Request) {
+	m.WorkloadPower.DeletePartialMatch(prometheus.Labels{
+		"estimator":           req.Name,
+		"estimator_namespace": req.Namespace,
+	})
+
+	m.WorkloadEmission.DeletePartialMatch(prometheus.Labels{
+		"estimator":           req.Name,
+		"estimator_namespace": req.Namespace,
+	})
 }
@@ -4,7 +4,9 @@
 package metrics
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -19,17 +21,17 @@ func TestMetrics_UpdateAndRegister(t *testing.T) {
 
 	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "my", Namespace: "ns"}}
 
-	m.Update(42.5, 10.25, 2, 5, req)
+	m.Update(42.5, 10.25, 2, 5, "TW", "electricitymaps", req)
 
 	// check power consumption gauge value
-	g := m.PowerConsumption.WithLabelValues("my", "ns")
+	g := m.PowerConsumption.WithLabelValues("my", "ns", "TW")
 	got := testutil.ToFloat64(g)
 	if got != 42.5 {
 		t.Fatalf("unexpected power consumption: got %v want %v", got, 42.5)
 	}
 
 	// check carbon emission gauge value
-	ge := m.CarbonEmission.WithLabelValues("my", "ns")
+	ge := m.CarbonEmission.WithLabelValues("my", "ns", "TW", "electricitymaps")
 	gotE := testutil.ToFloat64(ge)
 	if gotE != 10.25 {
 		t.Fatalf("unexpected carbon emission: got %v want %v", gotE, 10.25)
@@ -47,9 +49,214 @@ func TestMetrics_Delete(t *testing.T) {
 	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "to-delete", Namespace: "ns"}}
 
 	// set a value then delete
-	m.Update(1.0, 2.0, 1, 2, req)
+	m.Update(1.0, 2.0, 1, 2, "TW", "electricitymaps", req)
 	m.Delete(req)
 
 	// Deleting shouldn't panic; subsequent calls to WithLabelValues recreate metrics
-	_ = m.PowerConsumption.WithLabelValues("to-delete", "ns")
+	_ = m.PowerConsumption.WithLabelValues("to-delete", "ns", "TW")
+}
+
+func TestMetrics_UpdateReplacesZone(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "moved", Namespace: "ns"}}
+
+	m.Update(1.0, 2.0, 1, 2, "TW", "electricitymaps", req)
+	m.Update(3.0, 4.0, 1, 2, "JP", "electricitymaps", req)
+
+	if got := testutil.CollectAndCount(m.PowerConsumption); got != 1 {
+		t.Fatalf("expected a single power consumption series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.PowerConsumption.WithLabelValues("moved", "ns", "JP")); got != 3.0 {
+		t.Fatalf("unexpected power consumption: got %v want %v", got, 3.0)
+	}
+}
+
+func TestMetrics_UpdateReplacesSource(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "fallback", Namespace: "ns"}}
+
+	m.Update(1.0, 2.0, 1, 2, "TW", "electricitymaps", req)
+	m.Update(1.0, 5.0, 1, 2, "TW", "lastKnownGood", req)
+
+	if got := testutil.CollectAndCount(m.CarbonEmission); got != 1 {
+		t.Fatalf("expected a single carbon emission series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.CarbonEmission.WithLabelValues("fallback", "ns", "TW", "lastKnownGood")); got != 5.0 {
+		t.Fatalf("unexpected carbon emission: got %v want %v", got, 5.0)
+	}
+}
+
+func TestMetrics_UpdateFacility(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "facility", Namespace: "ns"}}
+
+	m.Update(100, 30, 1, 2, "TW", "electricitymaps", req)
+	m.UpdateFacility(150, "TW", req)
+
+	if got := testutil.ToFloat64(m.PowerConsumption.WithLabelValues("facility", "ns", "TW")); got != 100 {
+		t.Fatalf("unexpected IT power: got %v want %v", got, 100.0)
+	}
+	if got := testutil.ToFloat64(m.FacilityPower.WithLabelValues("facility", "ns", "TW")); got != 150 {
+		t.Fatalf("unexpected facility power: got %v want %v", got, 150.0)
+	}
+
+	// embodied emissions are only exported when enabled
+	if got := testutil.CollectAndCount(m.EmbodiedEmission); got != 0 {
+		t.Fatalf("expected no embodied emission series, got %d", got)
+	}
+	m.UpdateEmbodied(3.5, "TW", req)
+	if got := testutil.ToFloat64(m.EmbodiedEmission.WithLabelValues("facility", "ns", "TW")); got != 3.5 {
+		t.Fatalf("unexpected embodied emission: got %v want %v", got, 3.5)
+	}
+
+	m.Delete(req)
+	if got := testutil.CollectAndCount(m.FacilityPower); got != 0 {
+		t.Fatalf("expected facility power to be deleted, got %d series", got)
+	}
+}
+
+func TestMetrics_AddEnergyIsCumulative(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "energy", Namespace: "ns"}}
+
+	m.AddEnergy(0.5, 150, "TW", req)
+	// refreshing the gauges must not reset the counters
+	m.Update(100, 30, 1, 2, "TW", "electricitymaps", req)
+	m.AddEnergy(0.25, 75, "TW", req)
+
+	if got := testutil.ToFloat64(m.EnergyTotal.WithLabelValues("energy", "ns", "TW")); got != 0.75 {
+		t.Fatalf("unexpected energy total: got %v want %v", got, 0.75)
+	}
+	if got := testutil.ToFloat64(m.EmissionTotal.WithLabelValues("energy", "ns", "TW")); got != 225 {
+		t.Fatalf("unexpected emission total: got %v want %v", got, 225.0)
+	}
+
+	m.Delete(req)
+	if got := testutil.CollectAndCount(m.EnergyTotal); got != 0 {
+		t.Fatalf("expected energy counter to be deleted, got %d series", got)
+	}
+}
+
+func TestMetrics_UpdateNamespaces(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "attr", Namespace: "ns"}}
+
+	m.UpdateNamespaces(map[string]float64{"a": 60, "b": 40}, map[string]float64{"a": 18, "b": 12}, "TW", req)
+	// namespaces that disappear must not keep their last value
+	m.UpdateNamespaces(map[string]float64{"a": 70}, map[string]float64{"a": 21}, "TW", req)
+
+	if got := testutil.CollectAndCount(m.NamespacePower); got != 1 {
+		t.Fatalf("expected a single namespace series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.NamespaceEmission.WithLabelValues("attr", "ns", "a", "TW")); got != 21 {
+		t.Fatalf("unexpected namespace emission: got %v want %v", got, 21.0)
+	}
+}
+
+func TestMetrics_ObserveProvider(t *testing.T) {
+	m := SetupMetrics("tp")
+
+	m.ObserveCache("electricitymaps", true)
+	m.ObserveCache("electricitymaps", false)
+	m.ObserveRequest("electricitymaps", 200*time.Millisecond, nil)
+	m.ObserveRequest("electricitymaps", time.Second, errors.New("unavailable"))
+	m.ObserveQuota("electricitymaps", 42)
+
+	if got := testutil.ToFloat64(m.IntensityCacheRequests.WithLabelValues("electricitymaps", "hit")); got != 1 {
+		t.Fatalf("unexpected cache hits: got %v want %v", got, 1.0)
+	}
+	if got := testutil.CollectAndCount(m.ProviderRequestLatency); got != 1 {
+		t.Fatalf("expected a single latency series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.ProviderErrors.WithLabelValues("electricitymaps")); got != 1 {
+		t.Fatalf("unexpected provider errors: got %v want %v", got, 1.0)
+	}
+	if got := testutil.ToFloat64(m.ProviderQuotaRemaining.WithLabelValues("electricitymaps")); got != 42 {
+		t.Fatalf("unexpected remaining quota: got %v want %v", got, 42.0)
+	}
+}
+
+func TestMetrics_UpdateForecast(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "forecast", Namespace: "ns"}}
+	now := time.Date(2025, 5, 21, 10, 20, 0, 0, time.UTC)
+	hour := now.Truncate(time.Hour)
+
+	m.UpdateForecast(map[time.Time]float64{
+		hour.Add(-time.Hour):    400,
+		hour:                    300,
+		hour.Add(time.Hour):     250,
+		hour.Add(2 * time.Hour): 200,
+	}, now, "DE", req)
+
+	if got := testutil.CollectAndCount(m.Forecast); got != 3 {
+		t.Fatalf("expected three forecast series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.Forecast.WithLabelValues("forecast", "ns", "DE", "1h")); got != 250 {
+		t.Fatalf("unexpected 1h forecast: got %v want %v", got, 250.0)
+	}
+
+	m.UpdateForecast(nil, now, "DE", req)
+	if got := testutil.CollectAndCount(m.Forecast); got != 0 {
+		t.Fatalf("expected the forecast to be cleared, got %d series", got)
+	}
+}
+
+func TestMetrics_UpdateForecastError(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "forecast", Namespace: "ns"}}
+
+	m.UpdateForecastError(0.12, "DE", "electricitymaps", req)
+	m.UpdateForecastError(0.08, "DE", "builtin", req)
+	if got := testutil.CollectAndCount(m.ForecastError); got != 1 {
+		t.Fatalf("expected a single forecast error series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.ForecastError.WithLabelValues("forecast", "ns", "DE", "builtin")); got != 0.08 {
+		t.Fatalf("unexpected forecast error: got %v want %v", got, 0.08)
+	}
+
+	m.DeleteForecast(req)
+	if got := testutil.CollectAndCount(m.ForecastError); got != 0 {
+		t.Fatalf("expected the forecast error to be deleted, got %d series", got)
+	}
+}
+
+func TestMetrics_UpdateZones(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "zones", Namespace: "ns"}}
+
+	m.Update(300, 90, 100, 200, "DE", "electricitymaps", req)
+	m.UpdateFacility(300, "DE", req)
+	m.UpdateZones([]Zone{
+		{Zone: "DE", Source: "electricitymaps", Power: 200, Emission: 80},
+		{Zone: "FR", Source: "electricitymaps", Power: 100, Emission: 5},
+	}, req)
+
+	if got := testutil.CollectAndCount(m.CarbonEmission); got != 2 {
+		t.Fatalf("expected an emission series per zone, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.FacilityPower.WithLabelValues("zones", "ns", "FR")); got != 100 {
+		t.Fatalf("unexpected facility power of FR: got %v want %v", got, 100.0)
+	}
+	if got := testutil.ToFloat64(m.CarbonEmission.WithLabelValues("zones", "ns", "DE", "electricitymaps")); got != 80 {
+		t.Fatalf("unexpected emission of DE: got %v want %v", got, 80.0)
+	}
+}
+
+func TestMetrics_ObserveFailure(t *testing.T) {
+	m := SetupMetrics("tp")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "failing", Namespace: "ns"}}
+
+	m.ObserveFailure("PrometheusUnreachable", req)
+	m.ObserveFailure("PrometheusUnreachable", req)
+	m.ObserveFailure("SecretNotFound", req)
+
+	if got := testutil.ToFloat64(m.ReconcileFailures.WithLabelValues("failing", "ns", "PrometheusUnreachable")); got != 2 {
+		t.Fatalf("unexpected failures: got %v want %v", got, 2)
+	}
+
+	m.Delete(req)
+	if got := testutil.CollectAndCount(m.ReconcileFailures); got != 0 {
+		t.Fatalf("expected the failure counter to be deleted, got %d series", got)
+	}
 }
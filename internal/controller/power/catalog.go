@@ -0,0 +1,163 @@
+package power
+
+import (
+	_ "embed"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed catalog.yaml
+var embeddedCatalog []byte
+
+// Catalog holds the power profiles of cloud instance types, keyed by the value of the
+// node.kubernetes.io/instance-type label.
+type Catalog struct {
+	// Version of the catalog
+	Version string `json:"version"`
+	// Default is the per-vCPU profile of instance types missing from the catalog
+	Default VCPUProfile `json:"default"`
+	// InstanceTypes are the profiles of the known instance types
+	InstanceTypes map[string]InstanceType `json:"instanceTypes"`
+}
+
+// VCPUProfile is a power profile expressed per vCPU.
+type VCPUProfile struct {
+	IdleWattsPerVCPU float64 `json:"idleWattsPerVCPU,omitempty"`
+	MaxWattsPerVCPU  float64 `json:"maxWattsPerVCPU,omitempty"`
+	MemoryWattsPerGB float64 `json:"memoryWattsPerGB,omitempty"`
+	// EmbodiedKgCO2ePerVCPU is the manufacturing emissions of the host share of a vCPU in kgCO2e
+	EmbodiedKgCO2ePerVCPU float64 `json:"embodiedKgCO2ePerVCPU,omitempty"`
+	// LifespanYears is the lifespan the embodied emissions are amortized over
+	LifespanYears float64 `json:"lifespanYears,omitempty"`
+}
+
+// InstanceType is the power profile of a cloud instance type.
+type InstanceType struct {
+	// Provider of the instance type, e.g. aws, gcp or azure
+	Provider string  `json:"provider,omitempty"`
+	VCPUs    float64 `json:"vcpus,omitempty"`
+	// IdleWatts and MaxWatts are the power draw of the CPUs of the whole instance in W
+	IdleWatts float64 `json:"idleWatts,omitempty"`
+	MaxWatts  float64 `json:"maxWatts,omitempty"`
+	// MemoryWattsPerGB overrides the memory coefficient of the default profile
+	MemoryWattsPerGB float64 `json:"memoryWattsPerGB,omitempty"`
+	// EmbodiedKgCO2e is the manufacturing emissions of the host share of the instance in kgCO2e,
+	// LifespanYears overrides the lifespan of the default profile
+	EmbodiedKgCO2e float64 `json:"embodiedKgCO2e,omitempty"`
+	LifespanYears  float64 `json:"lifespanYears,omitempty"`
+}
+
+// DefaultCatalog returns the catalog embedded in the binary.
+func DefaultCatalog() Catalog {
+	catalog, err := ParseCatalog(embeddedCatalog)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded power catalog: %v", err))
+	}
+	return catalog
+}
+
+// ParseCatalog parses a YAML or JSON catalog.
+func ParseCatalog(data []byte) (Catalog, error) {
+	var catalog Catalog
+	if err := yaml.UnmarshalStrict(data, &catalog); err != nil {
+		return Catalog{}, err
+	}
+
+	for name, instanceType := range catalog.InstanceTypes {
+		if instanceType.MaxWatts < instanceType.IdleWatts {
+			return Catalog{}, fmt.Errorf("max watts of instance type %s is lower than its idle watts", name)
+		}
+	}
+	if catalog.Default.MaxWattsPerVCPU < catalog.Default.IdleWattsPerVCPU {
+		return Catalog{}, fmt.Errorf("max watts per vCPU of the default profile is lower than its idle watts")
+	}
+
+	return catalog, nil
+}
+
+// Merge returns the catalog with the entries of override added or updated. Only the fields set in
+// override are applied, e.g. an override may provide embodied emissions only.
+func (c Catalog) Merge(override Catalog) Catalog {
+	merged := Catalog{
+		Version:       c.Version,
+		Default:       c.Default.merge(override.Default),
+		InstanceTypes: make(map[string]InstanceType, len(c.InstanceTypes)+len(override.InstanceTypes)),
+	}
+	if override.Version != "" {
+		merged.Version = override.Version
+	}
+	for name, instanceType := range c.InstanceTypes {
+		merged.InstanceTypes[name] = instanceType
+	}
+	for name, instanceType := range override.InstanceTypes {
+		merged.InstanceTypes[name] = merged.InstanceTypes[name].merge(instanceType)
+	}
+	return merged
+}
+
+func (p VCPUProfile) merge(override VCPUProfile) VCPUProfile {
+	setIfNonZero(&p.IdleWattsPerVCPU, override.IdleWattsPerVCPU)
+	setIfNonZero(&p.MaxWattsPerVCPU, override.MaxWattsPerVCPU)
+	setIfNonZero(&p.MemoryWattsPerGB, override.MemoryWattsPerGB)
+	setIfNonZero(&p.EmbodiedKgCO2ePerVCPU, override.EmbodiedKgCO2ePerVCPU)
+	setIfNonZero(&p.LifespanYears, override.LifespanYears)
+	return p
+}
+
+func (t InstanceType) merge(override InstanceType) InstanceType {
+	if override.Provider != "" {
+		t.Provider = override.Provider
+	}
+	setIfNonZero(&t.VCPUs, override.VCPUs)
+	setIfNonZero(&t.IdleWatts, override.IdleWatts)
+	setIfNonZero(&t.MaxWatts, override.MaxWatts)
+	setIfNonZero(&t.MemoryWattsPerGB, override.MemoryWattsPerGB)
+	setIfNonZero(&t.EmbodiedKgCO2e, override.EmbodiedKgCO2e)
+	setIfNonZero(&t.LifespanYears, override.LifespanYears)
+	return t
+}
+
+func setIfNonZero(field *float64, value float64) {
+	if value != 0 {
+		*field = value
+	}
+}
+
+// Embodied returns the manufacturing emissions (kgCO2e) of a node and the lifespan (years) they are
+// amortized over. Instance types without embodied emissions in the catalog use the per-vCPU default
+// scaled by the CPU capacity of the node.
+func (c Catalog) Embodied(nodeLabels map[string]string, cpuCapacity float64) (kgCO2e, lifespanYears float64) {
+	kgCO2e = cpuCapacity * c.Default.EmbodiedKgCO2ePerVCPU
+	lifespanYears = c.Default.LifespanYears
+
+	if instanceType, found := c.InstanceTypes[nodeLabels[corev1.LabelInstanceTypeStable]]; found {
+		if instanceType.EmbodiedKgCO2e != 0 {
+			kgCO2e = instanceType.EmbodiedKgCO2e
+		} else if instanceType.VCPUs != 0 {
+			kgCO2e = instanceType.VCPUs * c.Default.EmbodiedKgCO2ePerVCPU
+		}
+		setIfNonZero(&lifespanYears, instanceType.LifespanYears)
+	}
+	return kgCO2e, lifespanYears
+}
+
+// Profile returns the profile of the instance type of the node. ok is false when the instance type is
+// unknown, in which case the default profile is scaled by the CPU capacity of the node.
+func (c Catalog) Profile(u NodeUsage) (profile Profile, ok bool) {
+	// entries may only provide embodied emissions
+	if instanceType, found := c.InstanceTypes[u.Labels[corev1.LabelInstanceTypeStable]]; found && instanceType.MaxWatts > 0 {
+		memory := instanceType.MemoryWattsPerGB
+		if memory == 0 {
+			memory = c.Default.MemoryWattsPerGB
+		}
+		return Profile{IdleWatts: instanceType.IdleWatts, MaxWatts: instanceType.MaxWatts, MemoryWatts: memory}, true
+	}
+
+	return Profile{
+		IdleWatts:   u.CPUCapacity * c.Default.IdleWattsPerVCPU,
+		MaxWatts:    u.CPUCapacity * c.Default.MaxWattsPerVCPU,
+		MemoryWatts: c.Default.MemoryWattsPerGB,
+	}, false
+}
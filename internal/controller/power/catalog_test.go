@@ -0,0 +1,60 @@
+//go:build unit
+// +build unit
+
+package power
+
+import (
+	"testing"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	catalog := DefaultCatalog()
+
+	if catalog.Version == "" {
+		t.Fatalf("embedded catalog has no version")
+	}
+	for _, instanceType := range []string{"m5.2xlarge", "n2-standard-4", "Standard_D4s_v3"} {
+		if _, ok := catalog.InstanceTypes[instanceType]; !ok {
+			t.Fatalf("embedded catalog misses instance type %s", instanceType)
+		}
+	}
+}
+
+func TestCatalogMerge(t *testing.T) {
+	override, err := ParseCatalog([]byte(`
+version: custom
+instanceTypes:
+  m5.2xlarge: {vcpus: 8, idleWatts: 30, maxWatts: 150}
+  on-prem.large: {vcpus: 32, idleWatts: 90, maxWatts: 350, memoryWattsPerGB: 0.3}
+`))
+	if err != nil {
+		t.Fatalf("ParseCatalog failed: %v", err)
+	}
+
+	base := DefaultCatalog()
+	merged := base.Merge(override)
+
+	if merged.Version != "custom" || merged.Default != base.Default {
+		t.Fatalf("unexpected version or default profile: %+v", merged)
+	}
+	if merged.InstanceTypes["m5.2xlarge"].IdleWatts != 30 {
+		t.Fatalf("override not applied: %+v", merged.InstanceTypes["m5.2xlarge"])
+	}
+	if _, ok := merged.InstanceTypes["c5.large"]; !ok {
+		t.Fatalf("base entries lost by merge")
+	}
+
+	profile, ok := merged.Profile(NodeUsage{Labels: map[string]string{"node.kubernetes.io/instance-type": "on-prem.large"}})
+	if !ok || profile.IdleWatts != 90 || profile.MaxWatts != 350 || profile.MemoryWatts != 0.3 {
+		t.Fatalf("unexpected profile: %+v (ok=%v)", profile, ok)
+	}
+}
+
+func TestParseCatalog_Invalid(t *testing.T) {
+	if _, err := ParseCatalog([]byte(`instanceTypes: {bad: {vcpus: 2, idleWatts: 10, maxWatts: 5}}`)); err == nil {
+		t.Fatalf("expected an error for max watts lower than idle watts")
+	}
+	if _, err := ParseCatalog([]byte(`instanceType: {}`)); err == nil {
+		t.Fatalf("expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,47 @@
+package power
+
+// Overhead is the datacenter overhead on top of the IT power of the nodes.
+type Overhead struct {
+	// PUE is the Power Usage Effectiveness of nodes without a more specific PUE. Zero means 1.
+	PUE float64
+	// NodeLabel is the node label whose value selects an entry of PUEByLabel, e.g. a datacenter label
+	NodeLabel string
+	// PUEByLabel maps values of NodeLabel to the PUE of the nodes carrying them
+	PUEByLabel map[string]float64
+	// Cooling and Networking are overheads relative to the IT power not covered by the PUE, e.g. 0.1 for 10%
+	Cooling    float64
+	Networking float64
+}
+
+// NodePUE returns the PUE of a node with the given labels.
+func (o Overhead) NodePUE(nodeLabels map[string]string) float64 {
+	if o.NodeLabel != "" {
+		if pue, ok := o.PUEByLabel[nodeLabels[o.NodeLabel]]; ok {
+			return pue
+		}
+	}
+	if o.PUE == 0 {
+		return 1
+	}
+	return o.PUE
+}
+
+// Apply returns the facility power of the IT power measurement:
+//
+//	P_facility = P_IT × PUE × (1 + cooling + networking)
+//
+// Every node uses the PUE matching its labels, looked up in nodeLabels by node name. Measurements
+// without a per-node breakdown use the default PUE.
+func (o Overhead) Apply(it Measurement, nodeLabels map[string]map[string]string) Measurement {
+	factor := 1 + o.Cooling + o.Networking
+
+	if len(it.Nodes) == 0 {
+		return Measurement{Total: it.Total * o.NodePUE(nil) * factor}
+	}
+
+	nodes := make(map[string]float64, len(it.Nodes))
+	for node, watts := range it.Nodes {
+		nodes[node] = watts * o.NodePUE(nodeLabels[node]) * factor
+	}
+	return NewMeasurement(nodes)
+}
@@ -0,0 +1,43 @@
+//go:build unit
+// +build unit
+
+package power
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOverheadApply(t *testing.T) {
+	overhead := Overhead{
+		PUE:        1.5,
+		NodeLabel:  "datacenter",
+		PUEByLabel: map[string]float64{"dc-1": 1.2},
+		Cooling:    0.05,
+		Networking: 0.05,
+	}
+	it := NewMeasurement(map[string]float64{"worker-1": 100, "worker-2": 100})
+	nodeLabels := map[string]map[string]string{"worker-1": {"datacenter": "dc-1"}}
+
+	facility := overhead.Apply(it, nodeLabels)
+
+	// worker-1: 100 * 1.2 * 1.1 = 132, worker-2 uses the default PUE: 100 * 1.5 * 1.1 = 165
+	if math.Abs(facility.Nodes["worker-1"]-132) > 1e-9 || math.Abs(facility.Nodes["worker-2"]-165) > 1e-9 {
+		t.Fatalf("unexpected facility node power: %v", facility.Nodes)
+	}
+	if math.Abs(facility.Total-297) > 1e-9 {
+		t.Fatalf("unexpected facility power: got %v want %v", facility.Total, 297)
+	}
+}
+
+func TestOverheadApply_WithoutNodes(t *testing.T) {
+	facility := Overhead{}.Apply(Measurement{Total: 80}, nil)
+	if facility.Total != 80 {
+		t.Fatalf("expected no overhead by default, got %v", facility.Total)
+	}
+
+	facility = Overhead{PUE: 1.25}.Apply(Measurement{Total: 80}, nil)
+	if math.Abs(facility.Total-100) > 1e-9 {
+		t.Fatalf("unexpected facility power: got %v want %v", facility.Total, 100)
+	}
+}
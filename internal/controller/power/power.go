@@ -0,0 +1,135 @@
+package power
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// bytesPerGB converts memory in bytes to GB, the unit memory coefficients are expressed in.
+const bytesPerGB = 1e9
+
+// Measurement is the power consumption of the cluster.
+type Measurement struct {
+	// Total power in W
+	Total float64
+	// Nodes is the power of every node in W. It is empty when the power source
+	// cannot break the consumption down per node.
+	Nodes map[string]float64
+}
+
+// NewMeasurement sums the power of every node into a measurement.
+func NewMeasurement(nodes map[string]float64) Measurement {
+	m := Measurement{Nodes: nodes}
+	for _, watts := range nodes {
+		m.Total += watts
+	}
+	return m
+}
+
+// NodeUsage is the resource usage of a node.
+type NodeUsage struct {
+	// Labels of the Node object, used to select its power coefficients or profile
+	Labels map[string]string
+	// CPUCores used on the node
+	CPUCores float64
+	// CPUCapacity is the number of vCPUs of the node
+	CPUCapacity float64
+	// CPUUtilization of the node, between 0 and 1
+	CPUUtilization float64
+	// MemoryBytes used on the node
+	MemoryBytes float64
+}
+
+// Coefficients is the power draw of a class of nodes per unit of used resource.
+type Coefficients struct {
+	// Selector matches the nodes of the class
+	Selector labels.Selector
+	// CPU is the power draw per used core in W
+	CPU float64
+	// Memory is the power draw per used GB of memory in W
+	Memory float64
+}
+
+// CoefficientsModel estimates power linearly from resource usage:
+//
+//	P = used cores × CPU coefficient + used GB × memory coefficient
+//
+// Each node uses the first class whose selector matches its labels.
+func CoefficientsModel(usage map[string]NodeUsage, classes []Coefficients) (Measurement, error) {
+	nodes := make(map[string]float64, len(usage))
+	var unmatched []string
+
+	for node, u := range usage {
+		class, ok := match(u.Labels, classes, func(c Coefficients) labels.Selector { return c.Selector })
+		if !ok {
+			unmatched = append(unmatched, node)
+			continue
+		}
+		nodes[node] = u.CPUCores*class.CPU + u.MemoryBytes/bytesPerGB*class.Memory
+	}
+
+	if len(unmatched) > 0 {
+		sort.Strings(unmatched)
+		return Measurement{}, fmt.Errorf("no power coefficients match nodes %v", unmatched)
+	}
+
+	return NewMeasurement(nodes), nil
+}
+
+// Profile is the power characteristics of a class of nodes.
+type Profile struct {
+	// Selector matches the nodes of the profile
+	Selector labels.Selector
+	// IdleWatts is the power draw of the CPUs of an idle node in W
+	IdleWatts float64
+	// MaxWatts is the power draw of the CPUs of a fully utilized node in W
+	MaxWatts float64
+	// MemoryWatts is the power draw per used GB of memory in W
+	MemoryWatts float64
+}
+
+// IdleMaxModel estimates the power of every node by interpolating between its idle and maximum
+// power draw, the approach of Cloud Carbon Footprint:
+//
+//	P = P_idle + CPU utilization × (P_max − P_idle) + used GB × memory coefficient
+//
+// Each node uses the first profile whose selector matches its labels, else the catalog profile of
+// its instance type, else the vCPU-based default profile of the catalog. The nodes estimated with
+// the default profile are returned sorted.
+func IdleMaxModel(usage map[string]NodeUsage, profiles []Profile, catalog Catalog) (Measurement, []string) {
+	nodes := make(map[string]float64, len(usage))
+	var unmatched []string
+
+	for node, u := range usage {
+		profile, ok := match(u.Labels, profiles, func(p Profile) labels.Selector { return p.Selector })
+		if !ok {
+			if profile, ok = catalog.Profile(u); !ok {
+				unmatched = append(unmatched, node)
+			}
+		}
+		nodes[node] = profile.Watts(u)
+	}
+
+	sort.Strings(unmatched)
+	return NewMeasurement(nodes), unmatched
+}
+
+// Watts returns the power draw of a node of the profile with the given usage.
+func (p Profile) Watts(u NodeUsage) float64 {
+	utilization := math.Min(math.Max(u.CPUUtilization, 0), 1)
+	return p.IdleWatts + utilization*(p.MaxWatts-p.IdleWatts) + u.MemoryBytes/bytesPerGB*p.MemoryWatts
+}
+
+// match returns the first item whose selector matches the node labels. A nil selector matches every node.
+func match[T any](nodeLabels map[string]string, items []T, selector func(T) labels.Selector) (T, bool) {
+	for _, item := range items {
+		if s := selector(item); s == nil || s.Matches(labels.Set(nodeLabels)) {
+			return item, true
+		}
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,79 @@
+//go:build unit
+// +build unit
+
+package power
+
+import (
+	"math"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestCoefficientsModel(t *testing.T) {
+	classes := []Coefficients{
+		{Selector: labels.SelectorFromSet(labels.Set{"node.kubernetes.io/instance-type": "m5.2xlarge"}), CPU: 12.5, Memory: 0.392},
+		{Selector: labels.Everything(), CPU: 15, Memory: 1.5},
+	}
+	usage := map[string]NodeUsage{
+		"worker-1": {Labels: map[string]string{"node.kubernetes.io/instance-type": "m5.2xlarge"}, CPUCores: 4, MemoryBytes: 16e9},
+		"worker-2": {CPUCores: 2, MemoryBytes: 2e9},
+	}
+
+	m, err := CoefficientsModel(usage, classes)
+	if err != nil {
+		t.Fatalf("CoefficientsModel failed: %v", err)
+	}
+
+	// worker-1: 4*12.5 + 16*0.392 = 56.272, worker-2: 2*15 + 2*1.5 = 33
+	if math.Abs(m.Nodes["worker-1"]-56.272) > 1e-9 || math.Abs(m.Nodes["worker-2"]-33) > 1e-9 {
+		t.Fatalf("unexpected node power: %v", m.Nodes)
+	}
+	if math.Abs(m.Total-89.272) > 1e-9 {
+		t.Fatalf("unexpected total power: got %v want %v", m.Total, 89.272)
+	}
+}
+
+func TestCoefficientsModel_UnmatchedNode(t *testing.T) {
+	classes := []Coefficients{
+		{Selector: labels.SelectorFromSet(labels.Set{"pool": "gpu"}), CPU: 20, Memory: 1},
+	}
+	usage := map[string]NodeUsage{"worker-1": {CPUCores: 1}}
+
+	if _, err := CoefficientsModel(usage, classes); err == nil {
+		t.Fatalf("expected an error for a node without coefficients")
+	}
+}
+
+func TestIdleMaxModel(t *testing.T) {
+	profiles := []Profile{
+		{Selector: labels.SelectorFromSet(labels.Set{"pool": "gpu"}), IdleWatts: 50, MaxWatts: 300, MemoryWatts: 0.392},
+	}
+	catalog := Catalog{
+		Default:       VCPUProfile{IdleWattsPerVCPU: 1, MaxWattsPerVCPU: 4, MemoryWattsPerGB: 0.5},
+		InstanceTypes: map[string]InstanceType{"m5.2xlarge": {VCPUs: 8, IdleWatts: 20, MaxWatts: 120}},
+	}
+	usage := map[string]NodeUsage{
+		// matches the spec profile before the catalog
+		"gpu-1":    {Labels: map[string]string{"pool": "gpu", "node.kubernetes.io/instance-type": "m5.2xlarge"}, CPUUtilization: 0.2},
+		"worker-1": {Labels: map[string]string{"node.kubernetes.io/instance-type": "m5.2xlarge"}, CPUUtilization: 0.5, MemoryBytes: 10e9},
+		"worker-2": {Labels: map[string]string{"node.kubernetes.io/instance-type": "x9.huge"}, CPUCapacity: 4, CPUUtilization: 1.5},
+	}
+
+	m, unmatched := IdleMaxModel(usage, profiles, catalog)
+
+	// gpu-1: 50 + 0.2*250 = 100, worker-1: 20 + 0.5*(120-20) + 10*0.5 = 75,
+	// worker-2: default profile of 4 vCPUs, utilization clamped to 1 = 16
+	want := map[string]float64{"gpu-1": 100, "worker-1": 75, "worker-2": 16}
+	for node, watts := range want {
+		if math.Abs(m.Nodes[node]-watts) > 1e-9 {
+			t.Fatalf("unexpected node power: got %v want %v", m.Nodes, want)
+		}
+	}
+	if math.Abs(m.Total-191) > 1e-9 {
+		t.Fatalf("unexpected total power: got %v want %v", m.Total, 191)
+	}
+	if len(unmatched) != 1 || unmatched[0] != "worker-2" {
+		t.Fatalf("unexpected unmatched nodes: %v", unmatched)
+	}
+}
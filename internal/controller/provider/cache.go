@@ -0,0 +1,341 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+)
+
+// ErrQuotaExceeded is returned when the request quota of a provider is used up for the current period.
+var ErrQuotaExceeded = errors.New("carbon intensity provider quota exceeded")
+
+// Quota is the number of upstream requests a provider allows per period.
+type Quota struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Observer receives the cache and upstream request events of a Cache, e.g. to export them as metrics.
+type Observer interface {
+	// ObserveCache records a cache lookup of the provider.
+	ObserveCache(provider string, hit bool)
+	// ObserveRequest records an upstream request of the provider and its outcome.
+	ObserveRequest(provider string, duration time.Duration, err error)
+	// ObserveQuota records the remaining requests of the provider in the current quota period.
+	ObserveQuota(provider string, remaining int)
+}
+
+// CacheOptions configures a Cache.
+type CacheOptions struct {
+	// TTL is how long a reading is served from the cache. Zero disables caching but keeps
+	// request coalescing, rate limiting and quota tracking.
+	TTL time.Duration
+	// RateLimits caps the upstream requests per second of each provider, with a burst of one.
+	// Providers without a limit are not rate limited.
+	RateLimits map[string]rate.Limit
+	// Quotas caps the upstream requests of each provider per period.
+	Quotas map[string]Quota
+	// Observer is notified of cache lookups and upstream requests, if set.
+	Observer Observer
+}
+
+// Cache shares carbon intensity readings and forecasts across estimators. Readings are keyed by provider,
+// zone, options and token, concurrent misses of the same key are coalesced into a single upstream request, and
+// upstream requests are rate limited and counted against the quota of the provider.
+//
+// A nil *Cache fetches every reading from the provider.
+type Cache struct {
+	ttl      time.Duration
+	observer Observer
+	group    singleflight.Group
+	now      func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	limiters map[string]*rate.Limiter
+	quotas   map[string]*quotaUsage
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+type quotaUsage struct {
+	Quota
+	used  int
+	start time.Time
+}
+
+// NewCache builds a cache.
+func NewCache(opts CacheOptions) *Cache {
+	c := &Cache{
+		ttl:      opts.TTL,
+		observer: opts.Observer,
+		now:      time.Now,
+		entries:  map[string]cacheEntry{},
+		limiters: make(map[string]*rate.Limiter, len(opts.RateLimits)),
+		quotas:   make(map[string]*quotaUsage, len(opts.Quotas)),
+	}
+	for name, limit := range opts.RateLimits {
+		c.limiters[name] = rate.NewLimiter(limit, 1)
+	}
+	for name, quota := range opts.Quotas {
+		c.quotas[name] = &quotaUsage{Quota: quota}
+	}
+	return c
+}
+
+// Fetch returns the carbon intensity of the zone from the named provider, from the cache while fresh.
+func (c *Cache) Fetch(ctx context.Context, name string, opts Options, zone string) (CarbonIntensity, error) {
+	if c == nil {
+		return fetch(ctx, name, opts, zone)
+	}
+
+	v, err := c.do(ctx, name, cacheKey(name, opts, zone), func() (interface{}, error) {
+		return fetch(ctx, name, opts, zone)
+	})
+	if err != nil {
+		return CarbonIntensity{}, err
+	}
+	return v.(CarbonIntensity), nil
+}
+
+// Forecast returns the forecast of the zone up to horizon from the named provider, from the cache while fresh.
+// It returns ErrForecastUnsupported for providers that do not publish forecasts.
+func (c *Cache) Forecast(
+	ctx context.Context,
+	name string,
+	opts Options,
+	zone string,
+	horizon time.Duration,
+) ([]ForecastPoint, error) {
+	p, err := New(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	forecaster, ok := p.(Forecaster)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrForecastUnsupported, name)
+	}
+	if c == nil {
+		return forecaster.Forecast(ctx, zone, horizon)
+	}
+
+	key := "forecast/" + horizon.String() + "\x00" + cacheKey(name, opts, zone)
+	v, err := c.do(ctx, name, key, func() (interface{}, error) {
+		return forecaster.Forecast(ctx, zone, horizon)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]ForecastPoint), nil
+}
+
+// do returns the cached value of the key, or coalesces the concurrent misses of the key into a single
+// rate limited upstream request of the provider.
+func (c *Cache) do(ctx context.Context, name, key string, request func() (interface{}, error)) (interface{}, error) {
+	if value, ok := c.lookup(key); ok {
+		c.observeCache(name, true)
+		return value, nil
+	}
+	c.observeCache(name, false)
+
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// a concurrent flight may have filled the entry in the meantime
+		if value, ok := c.lookup(key); ok {
+			return value, nil
+		}
+
+		if limiter := c.limiter(name); limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("rate limit of provider %s: %w", name, err)
+			}
+		}
+		if err := c.consumeQuota(name); err != nil {
+			return nil, err
+		}
+
+		start := c.now()
+		value, err := request()
+		if c.observer != nil {
+			c.observer.ObserveRequest(name, c.now().Sub(start), err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.store(key, value)
+		return value, nil
+	})
+	return value, err
+}
+
+func fetch(ctx context.Context, name string, opts Options, zone string) (CarbonIntensity, error) {
+	p, err := New(name, opts)
+	if err != nil {
+		return CarbonIntensity{}, err
+	}
+	return p.Fetch(ctx, zone)
+}
+
+func (c *Cache) lookup(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *Cache) store(key string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.entries[key] = cacheEntry{value: value, expires: now.Add(c.ttl)}
+	// drop expired entries so that zones no longer queried do not pile up
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+func (c *Cache) limiter(name string) *rate.Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limiters[name]
+}
+
+// consumeQuota counts an upstream request against the quota of the provider, if any.
+func (c *Cache) consumeQuota(name string) error {
+	c.mu.Lock()
+	usage, ok := c.quotas[name]
+	if !ok {
+		c.mu.Unlock()
+		return nil
+	}
+
+	now := c.now()
+	if usage.start.IsZero() || !now.Before(usage.start.Add(usage.Period)) {
+		usage.start, usage.used = now, 0
+	}
+	exceeded := usage.used >= usage.Limit
+	if !exceeded {
+		usage.used++
+	}
+	remaining, reset := usage.Limit-usage.used, usage.start.Add(usage.Period)
+	c.mu.Unlock()
+
+	if c.observer != nil {
+		c.observer.ObserveQuota(name, remaining)
+	}
+	if exceeded {
+		return fmt.Errorf("%w: %s allows %d requests per %s, resets at %s",
+			ErrQuotaExceeded, name, usage.Limit, usage.Period, reset.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (c *Cache) observeCache(name string, hit bool) {
+	if c.observer != nil {
+		c.observer.ObserveCache(name, hit)
+	}
+}
+
+// cacheKey identifies the readings of a provider configuration for a zone. The token is part of the key,
+// hashed so that it is not kept in memory in clear, so that estimators never share readings fetched with
+// another token, nor the errors of a token the provider rejects.
+func cacheKey(name string, opts Options, zone string) string {
+	keys := make([]string, 0, len(opts.Params))
+	for k := range opts.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('\x00')
+	b.WriteString(zone)
+	if opts.Token != "" {
+		sum := sha256.Sum256([]byte(opts.Token))
+		b.WriteString("\x00token=")
+		b.WriteString(hex.EncodeToString(sum[:]))
+	}
+	for _, k := range keys {
+		b.WriteByte('\x00')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(opts.Params[k])
+	}
+	return b.String()
+}
+
+// ParseRateLimits parses comma separated provider=requests-per-second pairs, e.g. "electricitymaps=0.5".
+func ParseRateLimits(s string) (map[string]rate.Limit, error) {
+	limits := map[string]rate.Limit{}
+	for _, pair := range splitPairs(s) {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q, expected provider=requests-per-second", pair)
+		}
+		limit, err := strconv.ParseFloat(value, 64)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid rate limit of provider %s: %q", name, value)
+		}
+		limits[name] = rate.Limit(limit)
+	}
+	return limits, nil
+}
+
+// ParseQuotas parses comma separated provider=requests/period pairs, e.g. "electricitymaps=1000/24h".
+func ParseQuotas(s string) (map[string]Quota, error) {
+	quotas := map[string]Quota{}
+	for _, pair := range splitPairs(s) {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid quota %q, expected provider=requests/period", pair)
+		}
+		limitValue, periodValue, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid quota of provider %s: %q, expected requests/period", name, value)
+		}
+		limit, err := strconv.Atoi(limitValue)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid quota limit of provider %s: %q", name, limitValue)
+		}
+		period, err := time.ParseDuration(periodValue)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid quota period of provider %s: %q", name, periodValue)
+		}
+		quotas[name] = Quota{Limit: limit, Period: period}
+	}
+	return quotas, nil
+}
+
+func splitPairs(s string) []string {
+	var pairs []string
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,230 @@
+//go:build unit
+// +build unit
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// countingServer serves Electricity Maps readings and counts the requests, blocking each one until
+// release is closed.
+func countingServer(t *testing.T, release <-chan struct{}) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"zone":"DE","carbonIntensity":302,"datetime":"2025-05-21T10:00:00.000Z"}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+type recordingObserver struct {
+	mu        sync.Mutex
+	hits      int
+	misses    int
+	requests  int
+	errors    int
+	remaining int
+}
+
+func (o *recordingObserver) ObserveCache(_ string, hit bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if hit {
+		o.hits++
+	} else {
+		o.misses++
+	}
+}
+
+func (o *recordingObserver) ObserveRequest(_ string, _ time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.requests++
+	if err != nil {
+		o.errors++
+	}
+}
+
+func (o *recordingObserver) ObserveQuota(_ string, remaining int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.remaining = remaining
+}
+
+func TestCache_ServesFreshReadings(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	ts, requests := countingServer(t, release)
+
+	observer := &recordingObserver{}
+	c := NewCache(CacheOptions{TTL: 5 * time.Minute, Observer: observer})
+	now := time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	opts := Options{Token: "a", Params: map[string]string{"url": ts.URL}}
+
+	for i := 0; i < 3; i++ {
+		ci, err := c.Fetch(context.Background(), ElectricityMaps, opts, "DE")
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if ci.Value != 302 {
+			t.Fatalf("unexpected value: got %v want %v", ci.Value, 302)
+		}
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("unexpected upstream requests: got %d want 1", got)
+	}
+	// readings fetched with a token are not served to estimators using another one
+	if _, err := c.Fetch(context.Background(), ElectricityMaps, Options{Token: "b", Params: opts.Params}, "DE"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Fatalf("unexpected upstream requests: got %d want 2", got)
+	}
+	if observer.hits != 2 || observer.misses != 2 || observer.requests != 2 {
+		t.Fatalf("unexpected observations: %+v", observer)
+	}
+
+	// other zones and expired readings go upstream
+	if _, err := c.Fetch(context.Background(), ElectricityMaps, opts, "FR"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	now = now.Add(5 * time.Minute)
+	if _, err := c.Fetch(context.Background(), ElectricityMaps, opts, "DE"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 4 {
+		t.Fatalf("unexpected upstream requests: got %d want 4", got)
+	}
+}
+
+func TestCache_CoalescesConcurrentMisses(t *testing.T) {
+	release := make(chan struct{})
+	ts, requests := countingServer(t, release)
+
+	c := NewCache(CacheOptions{TTL: time.Minute})
+	opts := Options{Params: map[string]string{"url": ts.URL}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Fetch(context.Background(), ElectricityMaps, opts, "DE")
+			errs <- err
+		}()
+	}
+	// let the callers pile up on the in-flight request
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("unexpected upstream requests: got %d want 1", got)
+	}
+}
+
+func TestCache_Quota(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	ts, requests := countingServer(t, release)
+
+	observer := &recordingObserver{}
+	c := NewCache(CacheOptions{
+		Quotas:   map[string]Quota{ElectricityMaps: {Limit: 2, Period: time.Hour}},
+		Observer: observer,
+	})
+	now := time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	opts := Options{Params: map[string]string{"url": ts.URL}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Fetch(context.Background(), ElectricityMaps, opts, "DE"); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	}
+	if _, err := c.Fetch(context.Background(), ElectricityMaps, opts, "DE"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Fatalf("unexpected upstream requests: got %d want 2", got)
+	}
+	if observer.remaining != 0 {
+		t.Fatalf("unexpected remaining quota: got %d want 0", observer.remaining)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := c.Fetch(context.Background(), ElectricityMaps, opts, "DE"); err != nil {
+		t.Fatalf("Fetch failed after the quota period: %v", err)
+	}
+	if observer.remaining != 1 {
+		t.Fatalf("unexpected remaining quota: got %d want 1", observer.remaining)
+	}
+}
+
+func TestCache_NilFetchesDirectly(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	ts, requests := countingServer(t, release)
+
+	var c *Cache
+	opts := Options{Params: map[string]string{"url": ts.URL}}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Fetch(context.Background(), ElectricityMaps, opts, "DE"); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Fatalf("unexpected upstream requests: got %d want 2", got)
+	}
+}
+
+func TestParseRateLimitsAndQuotas(t *testing.T) {
+	limits, err := ParseRateLimits("electricitymaps=0.5, watttime=2")
+	if err != nil {
+		t.Fatalf("ParseRateLimits failed: %v", err)
+	}
+	if limits[ElectricityMaps] != rate.Limit(0.5) || limits[WattTime] != rate.Limit(2) {
+		t.Fatalf("unexpected rate limits: %v", limits)
+	}
+
+	quotas, err := ParseQuotas("electricitymaps=1000/24h")
+	if err != nil {
+		t.Fatalf("ParseQuotas failed: %v", err)
+	}
+	if want := (Quota{Limit: 1000, Period: 24 * time.Hour}); quotas[ElectricityMaps] != want {
+		t.Fatalf("unexpected quotas: %v", quotas)
+	}
+
+	for _, s := range []string{"electricitymaps", "electricitymaps=0", "electricitymaps=fast"} {
+		if _, err := ParseRateLimits(s); err == nil {
+			t.Fatalf("expected an error for rate limits %q", s)
+		}
+	}
+	for _, s := range []string{"electricitymaps=1000", "electricitymaps=x/24h", "electricitymaps=1000/daily"} {
+		if _, err := ParseQuotas(s); err == nil {
+			t.Fatalf("expected an error for quotas %q", s)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"sustain_kube/internal/controller/retry"
+)
+
+const (
+	// CarbonIntensityUK is the registry name of the National Grid ESO Carbon Intensity API provider.
+	CarbonIntensityUK = "carbonintensityuk"
+
+	carbonIntensityUKURL = "https://api.carbonintensity.org.uk"
+	// carbonIntensityUKTimeLayout is the layout of the half-hour periods of the API
+	carbonIntensityUKTimeLayout = "2006-01-02T15:04Z"
+)
+
+func init() {
+	Register(CarbonIntensityUK, newCarbonIntensityUK)
+}
+
+// carbonIntensityUK fetches the carbon intensity of Great Britain from the free Carbon Intensity API
+// (https://carbonintensity.org.uk) and its forecast, nationally or for a region. It needs no token
+// and ignores the zone.
+//
+// Supported options:
+//
+//	url:      endpoint to query instead of the public API
+//	postcode: outward postcode (e.g. RG10) of the region to query
+//	regionID: ID (1-17) of the region to query, used when postcode is not set
+type carbonIntensityUK struct {
+	url      *url.URL
+	postcode string
+	regionID string
+	client   *http.Client
+}
+
+func newCarbonIntensityUK(opts Options) (CarbonIntensityProvider, error) {
+	targetURL := opts.Params["url"]
+	if targetURL == "" {
+		targetURL = carbonIntensityUKURL
+	}
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid carbon intensity uk url: %w", err)
+	}
+
+	return &carbonIntensityUK{
+		url:      u,
+		postcode: opts.Params["postcode"],
+		regionID: opts.Params["regionID"],
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// carbonIntensityUKPeriod is the intensity of a half-hour period.
+type carbonIntensityUKPeriod struct {
+	From      string `json:"from"`
+	Intensity struct {
+		Forecast *float64 `json:"forecast"`
+		Actual   *float64 `json:"actual"`
+		Index    string   `json:"index"`
+	} `json:"intensity"`
+}
+
+func (c *carbonIntensityUK) Fetch(ctx context.Context, _ string) (CarbonIntensity, error) {
+	// https://carbon-intensity.github.io/api-definitions/#carbon-intensity-api-v2-0-0
+	var u *url.URL
+	switch {
+	case c.postcode != "":
+		u = c.url.JoinPath("/regional/postcode", c.postcode)
+	case c.regionID != "":
+		u = c.url.JoinPath("/regional/regionid", c.regionID)
+	default:
+		u = c.url.JoinPath("/intensity")
+	}
+
+	periods, err := c.get(ctx, u)
+	if err != nil {
+		return CarbonIntensity{}, err
+	}
+	period := periods[0]
+
+	ci := CarbonIntensity{
+		Unit:      UnitGramsPerKWh,
+		Timestamp: time.Now(),
+		Source:    CarbonIntensityUK,
+		Signal:    SignalAverage,
+		Index:     period.Intensity.Index,
+		Forecast:  period.Intensity.Forecast,
+		Actual:    period.Intensity.Actual,
+	}
+	switch {
+	case period.Intensity.Actual != nil:
+		ci.Value = *period.Intensity.Actual
+	case period.Intensity.Forecast != nil:
+		ci.Value = *period.Intensity.Forecast
+	default:
+		return CarbonIntensity{}, fmt.Errorf("carbon intensity API returned neither an actual nor a forecast intensity")
+	}
+	if t, err := time.Parse(carbonIntensityUKTimeLayout, period.From); err == nil {
+		ci.Timestamp = t
+	}
+
+	return ci, nil
+}
+
+// Forecast returns the forecast of the half-hour periods of the next 24 or 48 hours.
+func (c *carbonIntensityUK) Forecast(ctx context.Context, _ string, horizon time.Duration) ([]ForecastPoint, error) {
+	now := time.Now().UTC()
+	from := now.Format(carbonIntensityUKTimeLayout)
+	window := "fw24h"
+	if horizon > 24*time.Hour {
+		window = "fw48h"
+	}
+
+	var u *url.URL
+	switch {
+	case c.postcode != "":
+		u = c.url.JoinPath("/regional/intensity", from, window, "postcode", c.postcode)
+	case c.regionID != "":
+		u = c.url.JoinPath("/regional/intensity", from, window, "regionid", c.regionID)
+	default:
+		u = c.url.JoinPath("/intensity", from, window)
+	}
+
+	periods, err := c.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]ForecastPoint, 0, len(periods))
+	for _, period := range periods {
+		t, err := time.Parse(carbonIntensityUKTimeLayout, period.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid carbon intensity period %q: %w", period.From, err)
+		}
+		value := period.Intensity.Forecast
+		if value == nil {
+			value = period.Intensity.Actual
+		}
+		if value == nil {
+			continue
+		}
+		points = append(points, ForecastPoint{Timestamp: t, Value: *value})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+
+	return within(points, now, now.Add(horizon), 30*time.Minute), nil
+}
+
+// get queries an endpoint of the API and returns the periods of the response.
+func (c *carbonIntensityUK) get(ctx context.Context, u *url.URL) ([]carbonIntensityUKPeriod, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Log.Error(err, "Error closing carbon intensity API response body")
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read carbon intensity response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, retry.NewStatusError(resp.StatusCode, "carbon intensity API error: %s", string(body))
+	}
+
+	return c.parse(body)
+}
+
+// parse returns the periods of a national or regional response. Regional responses nest the
+// periods of the region in its data, which is a list for the current period and an object for
+// forecasts.
+func (c *carbonIntensityUK) parse(body []byte) ([]carbonIntensityUKPeriod, error) {
+	var periods []carbonIntensityUKPeriod
+
+	if c.postcode != "" || c.regionID != "" {
+		type region struct {
+			Data []carbonIntensityUKPeriod `json:"data"`
+		}
+		var result struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse carbon intensity JSON: %w", err)
+		}
+
+		var regions []region
+		if data := bytes.TrimSpace(result.Data); len(data) > 0 && data[0] == '{' {
+			regions = make([]region, 1)
+			if err := json.Unmarshal(data, &regions[0]); err != nil {
+				return nil, fmt.Errorf("failed to parse carbon intensity JSON: %w", err)
+			}
+		} else if len(data) > 0 {
+			if err := json.Unmarshal(data, &regions); err != nil {
+				return nil, fmt.Errorf("failed to parse carbon intensity JSON: %w", err)
+			}
+		}
+		if len(regions) > 0 {
+			periods = regions[0].Data
+		}
+	} else {
+		var result struct {
+			Data []carbonIntensityUKPeriod `json:"data"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse carbon intensity JSON: %w", err)
+		}
+		periods = result.Data
+	}
+
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("carbon intensity API returned no data")
+	}
+	return periods, nil
+}
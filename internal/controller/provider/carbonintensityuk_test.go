@@ -0,0 +1,80 @@
+//go:build unit
+// +build unit
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func fakeCarbonIntensityUK(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/intensity":
+			_, _ = w.Write([]byte(`{"data":[{"from":"2025-05-21T10:00Z","to":"2025-05-21T10:30Z",` +
+				`"intensity":{"forecast":266,"actual":263,"index":"moderate"}}]}`))
+		case "/regional/postcode/RG10", "/regional/regionid/3":
+			_, _ = w.Write([]byte(`{"data":[{"regionid":12,"shortname":"South England","postcode":"RG10","data":[` +
+				`{"from":"2025-05-21T10:00Z","to":"2025-05-21T10:30Z","intensity":{"forecast":95,"index":"low"}}]}]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCarbonIntensityUK_National(t *testing.T) {
+	ts := fakeCarbonIntensityUK(t)
+	defer ts.Close()
+
+	p, err := New(CarbonIntensityUK, Options{Params: map[string]string{"url": ts.URL}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ci, err := p.Fetch(context.Background(), "GB")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	// the actual intensity is preferred over the forecast
+	if ci.Value != 263 || ci.Index != "moderate" || ci.Signal != SignalAverage {
+		t.Fatalf("unexpected intensity: %+v", ci)
+	}
+	if ci.Forecast == nil || *ci.Forecast != 266 || ci.Actual == nil || *ci.Actual != 263 {
+		t.Fatalf("unexpected forecast/actual: %v %v", ci.Forecast, ci.Actual)
+	}
+	if want := time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC); !ci.Timestamp.Equal(want) {
+		t.Fatalf("unexpected timestamp: got %v want %v", ci.Timestamp, want)
+	}
+}
+
+func TestCarbonIntensityUK_Regional(t *testing.T) {
+	ts := fakeCarbonIntensityUK(t)
+	defer ts.Close()
+
+	for _, params := range []map[string]string{
+		{"url": ts.URL, "postcode": "RG10"},
+		{"url": ts.URL, "regionID": "3"},
+	} {
+		p, err := New(CarbonIntensityUK, Options{Params: params})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+
+		ci, err := p.Fetch(context.Background(), "GB")
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+
+		// regional intensities are forecasts only
+		if ci.Value != 95 || ci.Index != "low" || ci.Actual != nil {
+			t.Fatalf("unexpected regional intensity for %v: %+v", params, ci)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"sustain_kube/internal/controller/retry"
+)
+
+const (
+	// ElectricityMaps is the registry name of the Electricity Maps provider.
+	ElectricityMaps = "electricitymaps"
+
+	electricityMapsURL = "https://api.electricitymap.org/v3/carbon-intensity/latest"
+)
+
+func init() {
+	Register(ElectricityMaps, newElectricityMaps)
+}
+
+// electricityMaps fetches the latest carbon intensity and its forecast from the Electricity Maps API.
+//
+// Supported options:
+//
+//	url: endpoint to query instead of the public API
+type electricityMaps struct {
+	url    *url.URL
+	token  string
+	client *http.Client
+}
+
+func newElectricityMaps(opts Options) (CarbonIntensityProvider, error) {
+	targetURL := opts.Params["url"]
+	if targetURL == "" {
+		targetURL = os.Getenv("CARBON_INTENSITY_URL") // provide to E2E test
+	}
+	if targetURL == "" {
+		targetURL = electricityMapsURL
+	}
+
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid electricity maps url: %w", err)
+	}
+
+	return &electricityMaps{
+		url:    u,
+		token:  opts.Token,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+func (e *electricityMaps) Fetch(ctx context.Context, zone string) (CarbonIntensity, error) {
+	u := *e.url
+	q := u.Query()
+	q.Set("zone", zone)
+	u.RawQuery = q.Encode()
+
+	// https://static.electricitymaps.com/api/docs/index.html#live-carbon-intensity
+	var result electricityMapsPoint
+	if err := e.get(ctx, &u, &result); err != nil {
+		return CarbonIntensity{}, err
+	}
+
+	timestamp := time.Now()
+	if t, err := time.Parse(time.RFC3339, result.Datetime); err == nil {
+		timestamp = t
+	}
+
+	return CarbonIntensity{
+		Value:     result.CarbonIntensity,
+		Unit:      UnitGramsPerKWh,
+		Timestamp: timestamp,
+		Source:    ElectricityMaps,
+		Signal:    SignalAverage,
+	}, nil
+}
+
+// Forecast returns the hourly forecast of the zone. The forecast endpoint is next to the latest one.
+func (e *electricityMaps) Forecast(ctx context.Context, zone string, horizon time.Duration) ([]ForecastPoint, error) {
+	u := *e.url
+	u.Path = strings.TrimSuffix(u.Path, "/latest") + "/forecast"
+	q := u.Query()
+	q.Set("zone", zone)
+	q.Set("horizonHours", strconv.Itoa(int(math.Ceil(horizon.Hours()))))
+	u.RawQuery = q.Encode()
+
+	// https://static.electricitymaps.com/api/docs/index.html#carbon-intensity-forecast
+	var result struct {
+		Forecast []electricityMapsPoint `json:"forecast"`
+	}
+	if err := e.get(ctx, &u, &result); err != nil {
+		return nil, err
+	}
+
+	points := make([]ForecastPoint, 0, len(result.Forecast))
+	for _, point := range result.Forecast {
+		t, err := time.Parse(time.RFC3339, point.Datetime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid carbon intensity forecast datetime %q: %w", point.Datetime, err)
+		}
+		points = append(points, ForecastPoint{Timestamp: t, Value: point.CarbonIntensity})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+
+	now := time.Now()
+	return within(points, now, now.Add(horizon), time.Hour), nil
+}
+
+// electricityMapsPoint is a carbon intensity reading of the Electricity Maps API.
+type electricityMapsPoint struct {
+	CarbonIntensity float64 `json:"carbonIntensity"`
+	Datetime        string  `json:"datetime"`
+}
+
+// get sends an authenticated GET request and decodes the JSON response into out.
+func (e *electricityMaps) get(ctx context.Context, u *url.URL, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("auth-token", e.token)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Log.Error(err, "Error closing carbon intensity API response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return retry.NewStatusError(resp.StatusCode, "carbon intensity API error: %s", string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read carbon intensity response: %w", err)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse carbon intensity JSON: %w", err)
+	}
+	return nil
+}
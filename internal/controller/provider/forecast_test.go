@@ -0,0 +1,149 @@
+//go:build unit
+// +build unit
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func forecastOf(t *testing.T, p CarbonIntensityProvider, horizon time.Duration) []ForecastPoint {
+	t.Helper()
+	forecaster, ok := p.(Forecaster)
+	if !ok {
+		t.Fatalf("%T does not publish forecasts", p)
+	}
+	points, err := forecaster.Forecast(context.Background(), "DE", horizon)
+	if err != nil {
+		t.Fatalf("Forecast failed: %v", err)
+	}
+	return points
+}
+
+func TestElectricityMaps_Forecast(t *testing.T) {
+	hour := time.Now().UTC().Truncate(time.Hour)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/carbon-intensity/forecast" || r.URL.Query().Get("horizonHours") != "3" {
+			t.Errorf("unexpected forecast request: %s", r.URL)
+		}
+		var points []string
+		for h := -1; h < 5; h++ {
+			points = append(points, fmt.Sprintf(`{"carbonIntensity":%d,"datetime":%q}`,
+				300+h, hour.Add(time.Duration(h)*time.Hour).Format(time.RFC3339)))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"zone":"DE","forecast":[` + strings.Join(points, ",") + `]}`))
+	}))
+	defer ts.Close()
+
+	p, err := New(ElectricityMaps, Options{Params: map[string]string{"url": ts.URL + "/v3/carbon-intensity/latest"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// the current hour and the next two or three, depending on the minute
+	points := forecastOf(t, p, 3*time.Hour)
+	if len(points) < 3 || !points[0].Timestamp.Equal(hour) || points[0].Value != 300 {
+		t.Fatalf("unexpected forecast: %+v", points)
+	}
+}
+
+func TestWattTime_Forecast(t *testing.T) {
+	start := time.Now().UTC().Truncate(5 * time.Minute)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			_, _ = w.Write([]byte(`{"token":"forecast-token"}`))
+		case "/v3/forecast":
+			if r.URL.Query().Get("horizon_hours") != "1" {
+				t.Errorf("unexpected horizon: %s", r.URL.RawQuery)
+			}
+			var points []string
+			for i := 0; i < 12; i++ {
+				points = append(points, fmt.Sprintf(`{"point_time":%q,"value":1000}`,
+					start.Add(time.Duration(i)*5*time.Minute).Format(time.RFC3339)))
+			}
+			_, _ = w.Write([]byte(`{"data":[` + strings.Join(points, ",") + `]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p, err := New(WattTime, Options{Token: "secret", Params: map[string]string{
+		"url": ts.URL, "username": "forecaster", "region": "CAISO_NORTH",
+	}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	points := forecastOf(t, p, time.Hour)
+	if len(points) != 12 || math.Abs(points[0].Value-453.59237) > 1e-9 {
+		t.Fatalf("unexpected forecast: %+v", points)
+	}
+}
+
+func TestCarbonIntensityUK_RegionalForecast(t *testing.T) {
+	period := time.Now().UTC().Truncate(30 * time.Minute)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/regional/intensity/") || !strings.HasSuffix(r.URL.Path, "/fw24h/postcode/RG10") {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var periods []string
+		for i := 0; i < 4; i++ {
+			periods = append(periods, fmt.Sprintf(`{"from":%q,"intensity":{"forecast":%d,"index":"low"}}`,
+				period.Add(time.Duration(i)*30*time.Minute).Format(carbonIntensityUKTimeLayout), 90+i))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"regionid":12,"postcode":"RG10","data":[` + strings.Join(periods, ",") + `]}}`))
+	}))
+	defer ts.Close()
+
+	p, err := New(CarbonIntensityUK, Options{Params: map[string]string{"url": ts.URL, "postcode": "RG10"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	points := forecastOf(t, p, 24*time.Hour)
+	if len(points) != 4 || points[0].Value != 90 || points[3].Value != 93 {
+		t.Fatalf("unexpected forecast: %+v", points)
+	}
+}
+
+func TestStatic_Forecast(t *testing.T) {
+	p := newStaticAt(t, map[string]string{"hourly": hourlyProfile(100)},
+		time.Date(2025, 5, 21, 22, 30, 0, 0, time.UTC))
+
+	points := forecastOf(t, p, 3*time.Hour)
+	want := []float64{122, 123, 100, 101}
+	if len(points) != len(want) {
+		t.Fatalf("unexpected forecast: %+v", points)
+	}
+	for i, point := range points {
+		if point.Value != want[i] {
+			t.Fatalf("unexpected value of hour %d: got %v want %v", i, point.Value, want[i])
+		}
+	}
+}
+
+func TestHourly(t *testing.T) {
+	start := time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC)
+	points := []ForecastPoint{
+		{Timestamp: start, Value: 100},
+		{Timestamp: start.Add(30 * time.Minute), Value: 200},
+		{Timestamp: start.Add(time.Hour), Value: 50},
+	}
+
+	hourly := Hourly(points)
+	if len(hourly) != 2 || hourly[0].Value != 150 || hourly[1].Value != 50 || !hourly[1].Timestamp.Equal(start.Add(time.Hour)) {
+		t.Fatalf("unexpected hourly forecast: %+v", hourly)
+	}
+}
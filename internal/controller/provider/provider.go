@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UnitGramsPerKWh is the unit every built-in provider reports carbon intensity in.
+const UnitGramsPerKWh = "gCO2eq/kWh"
+
+// Signal types of a carbon intensity reading.
+const (
+	// SignalAverage is the average emissions of the electricity consumed in the zone.
+	SignalAverage = "average"
+	// SignalMarginal is the emissions of the power plants responding to a change in demand (MOER).
+	SignalMarginal = "marginal"
+)
+
+// CarbonIntensity is a single carbon intensity reading of an electricity grid zone.
+type CarbonIntensity struct {
+	// Value is the carbon intensity, expressed in Unit.
+	Value float64
+	// Unit of Value, e.g. gCO2eq/kWh.
+	Unit string
+	// Timestamp is the time the reading refers to, as reported by the source.
+	Timestamp time.Time
+	// Source is the name of the provider that produced the reading.
+	Source string
+	// Signal is the type of the reading, SignalAverage or SignalMarginal.
+	Signal string
+	// Index is a relative level of the intensity reported by the source, if any,
+	// e.g. a percentile of the recent readings of the zone or a band such as "moderate".
+	Index string
+	// Forecast and Actual are the forecast and measured intensity of the period, for sources
+	// reporting them separately. Value is the actual intensity when known, else the forecast.
+	Forecast *float64
+	Actual   *float64
+}
+
+// CarbonIntensityProvider fetches the carbon intensity of an electricity grid zone.
+type CarbonIntensityProvider interface {
+	Fetch(ctx context.Context, zone string) (CarbonIntensity, error)
+}
+
+// ErrForecastUnsupported is returned for providers that do not publish forecasts.
+var ErrForecastUnsupported = errors.New("carbon intensity provider does not publish forecasts")
+
+// ForecastPoint is the forecast carbon intensity of the period starting at Timestamp, in gCO2eq/kWh.
+type ForecastPoint struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Forecaster is implemented by providers publishing carbon intensity forecasts.
+type Forecaster interface {
+	// Forecast returns the forecast of the zone from the current period up to horizon from now, sorted by time.
+	Forecast(ctx context.Context, zone string, horizon time.Duration) ([]ForecastPoint, error)
+}
+
+// Hourly averages the points falling in the same hour, so that forecasts of any resolution
+// have at most one point per hour.
+func Hourly(points []ForecastPoint) []ForecastPoint {
+	var hourly []ForecastPoint
+	var count int
+	for _, point := range points {
+		hour := point.Timestamp.Truncate(time.Hour)
+		if n := len(hourly); n > 0 && hourly[n-1].Timestamp.Equal(hour) {
+			count++
+			hourly[n-1].Value += (point.Value - hourly[n-1].Value) / float64(count)
+			continue
+		}
+		hourly = append(hourly, ForecastPoint{Timestamp: hour, Value: point.Value})
+		count = 1
+	}
+	return hourly
+}
+
+// within returns the points of a sorted forecast whose period has not ended before from and starts before to.
+// step is the length of the periods.
+func within(points []ForecastPoint, from, to time.Time, step time.Duration) []ForecastPoint {
+	var kept []ForecastPoint
+	for _, point := range points {
+		if point.Timestamp.Add(step).After(from) && point.Timestamp.Before(to) {
+			kept = append(kept, point)
+		}
+	}
+	return kept
+}
+
+// Options configures a provider instance.
+type Options struct {
+	// Token is the API credential read from the estimator's Secret, if any.
+	Token string
+	// Params holds provider specific settings taken from spec.provider.options.
+	Params map[string]string
+}
+
+// Factory builds a provider from its options.
+type Factory func(opts Options) (CarbonIntensityProvider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a provider available under the given name.
+// It panics if a provider with the same name is already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("carbon intensity provider %q already registered", name))
+	}
+	registry[name] = factory
+}
+
+// New builds the provider registered under the given name.
+func New(name string, opts Options) (CarbonIntensityProvider, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown carbon intensity provider %q", name)
+	}
+
+	return factory(opts)
+}
+
+// Names returns the names of all registered providers in alphabetical order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
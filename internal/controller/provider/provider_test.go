@@ -0,0 +1,81 @@
+//go:build unit
+// +build unit
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew_UnknownProvider(t *testing.T) {
+	if _, err := New("does-not-exist", Options{}); err == nil {
+		t.Fatalf("expected error for unknown provider")
+	}
+}
+
+func TestNames_ContainsBuiltins(t *testing.T) {
+	found := false
+	for _, name := range Names() {
+		if name == ElectricityMaps {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected %q to be registered, got %v", ElectricityMaps, Names())
+	}
+}
+
+func TestElectricityMaps_Fetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("zone"); got != "DE" {
+			t.Errorf("unexpected zone: got %q want %q", got, "DE")
+		}
+		if got := r.Header.Get("auth-token"); got != "secret" {
+			t.Errorf("unexpected auth-token: got %q want %q", got, "secret")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"zone":"DE","carbonIntensity":302,"datetime":"2025-05-21T10:00:00.000Z"}`))
+	}))
+	defer ts.Close()
+
+	p, err := New(ElectricityMaps, Options{Token: "secret", Params: map[string]string{"url": ts.URL}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ci, err := p.Fetch(context.Background(), "DE")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if ci.Value != 302 {
+		t.Fatalf("unexpected value: got %v want %v", ci.Value, 302)
+	}
+	if ci.Unit != UnitGramsPerKWh || ci.Source != ElectricityMaps {
+		t.Fatalf("unexpected unit/source: %q %q", ci.Unit, ci.Source)
+	}
+	if want := time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC); !ci.Timestamp.Equal(want) {
+		t.Fatalf("unexpected timestamp: got %v want %v", ci.Timestamp, want)
+	}
+}
+
+func TestElectricityMaps_FetchError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid token"}`))
+	}))
+	defer ts.Close()
+
+	p, err := New(ElectricityMaps, Options{Params: map[string]string{"url": ts.URL}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if _, err := p.Fetch(context.Background(), "DE"); err == nil {
+		t.Fatalf("expected error for non-200 response")
+	}
+}
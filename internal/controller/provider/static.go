@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	// the time zone database is embedded, the controller image may not ship one
+	_ "time/tzdata"
+)
+
+// Static is the registry name of the offline provider, for clusters that cannot reach any API.
+const Static = "static"
+
+func init() {
+	Register(Static, newStatic)
+}
+
+// static serves carbon intensity in gCO2eq/kWh from its options, which may be read from a ConfigMap.
+// It ignores the zone. The first configured source wins:
+//
+//	csv:       time series of "timestamp,value" lines (RFC3339 timestamps), the latest value
+//	           at or before now is used
+//	monday..sunday: 24 comma-separated hourly values of that day of the week
+//	hourly:    24 comma-separated hourly values of any day
+//	value:     constant value
+//
+// Supported options besides the sources:
+//
+//	timezone: IANA time zone the hourly profiles are evaluated in, defaults to UTC
+type static struct {
+	value    *float64
+	hourly   []float64
+	weekly   map[time.Weekday][]float64
+	series   []seriesPoint
+	location *time.Location
+	now      func() time.Time
+}
+
+type seriesPoint struct {
+	time  time.Time
+	value float64
+}
+
+func newStatic(opts Options) (CarbonIntensityProvider, error) {
+	s := &static{
+		weekly:   map[time.Weekday][]float64{},
+		location: time.UTC,
+		now:      time.Now,
+	}
+
+	if tz := opts.Params["timezone"]; tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+		s.location = location
+	}
+
+	if v := opts.Params["value"]; v != "" {
+		value, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		s.value = &value
+	}
+
+	if v := opts.Params["hourly"]; v != "" {
+		hourly, err := parseHourly(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hourly profile: %w", err)
+		}
+		s.hourly = hourly
+	}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		key := strings.ToLower(day.String())
+		if v := opts.Params[key]; v != "" {
+			hourly, err := parseHourly(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s profile: %w", key, err)
+			}
+			s.weekly[day] = hourly
+		}
+	}
+
+	if v := opts.Params["csv"]; v != "" {
+		series, err := parseSeries(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv time series: %w", err)
+		}
+		s.series = series
+	}
+
+	if s.value == nil && s.hourly == nil && len(s.weekly) == 0 && s.series == nil {
+		return nil, fmt.Errorf("the static provider requires one of the csv, hourly, day of week or value options")
+	}
+
+	return s, nil
+}
+
+func (s *static) Fetch(_ context.Context, _ string) (CarbonIntensity, error) {
+	point, err := s.at(s.now())
+	if err != nil {
+		return CarbonIntensity{}, err
+	}
+
+	return CarbonIntensity{
+		Value:     point.value,
+		Unit:      UnitGramsPerKWh,
+		Timestamp: point.time,
+		Source:    Static,
+		Signal:    SignalAverage,
+	}, nil
+}
+
+// Forecast returns the points of the csv time series up to horizon from now, or the hourly values
+// of the profiles.
+func (s *static) Forecast(_ context.Context, _ string, horizon time.Duration) ([]ForecastPoint, error) {
+	now := s.now()
+	end := now.Add(horizon)
+
+	if s.series != nil {
+		var points []ForecastPoint
+		// the current point, then the upcoming ones
+		if current, err := s.at(now); err == nil {
+			points = append(points, ForecastPoint{Timestamp: current.time, Value: current.value})
+		}
+		for _, point := range s.series {
+			if point.time.After(now) && point.time.Before(end) {
+				points = append(points, ForecastPoint{Timestamp: point.time, Value: point.value})
+			}
+		}
+		return points, nil
+	}
+
+	local := now.In(s.location)
+	var points []ForecastPoint
+	start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, s.location)
+	for t := start; t.Before(end); t = t.Add(time.Hour) {
+		point, err := s.at(t)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, ForecastPoint{Timestamp: t, Value: point.value})
+	}
+	return points, nil
+}
+
+// at returns the value in effect at t, and the time it took effect for time series.
+func (s *static) at(t time.Time) (seriesPoint, error) {
+	if s.series != nil {
+		// the latest point at or before t
+		i := sort.Search(len(s.series), func(i int) bool { return s.series[i].time.After(t) })
+		if i == 0 {
+			return seriesPoint{}, fmt.Errorf("the csv time series starts after %s", t.Format(time.RFC3339))
+		}
+		return s.series[i-1], nil
+	}
+
+	local := t.In(s.location)
+	if hourly, ok := s.weekly[local.Weekday()]; ok {
+		return seriesPoint{time: t, value: hourly[local.Hour()]}, nil
+	}
+	if s.hourly != nil {
+		return seriesPoint{time: t, value: s.hourly[local.Hour()]}, nil
+	}
+	if s.value != nil {
+		return seriesPoint{time: t, value: *s.value}, nil
+	}
+
+	return seriesPoint{}, fmt.Errorf("no static carbon intensity for %s", local.Weekday())
+}
+
+// parseHourly parses 24 comma-separated values.
+func parseHourly(s string) ([]float64, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 24 {
+		return nil, fmt.Errorf("expected 24 values, got %d", len(fields))
+	}
+
+	hourly := make([]float64, 0, 24)
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		hourly = append(hourly, value)
+	}
+	return hourly, nil
+}
+
+// parseSeries parses "timestamp,value" records sorted by time. A header line is skipped.
+func parseSeries(s string) ([]seriesPoint, error) {
+	reader := csv.NewReader(strings.NewReader(s))
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var series []seriesPoint
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		value, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		series = append(series, seriesPoint{time: t, value: value})
+	}
+
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].time.Before(series[j].time) })
+	return series, nil
+}
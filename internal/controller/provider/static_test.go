@@ -0,0 +1,102 @@
+//go:build unit
+// +build unit
+
+package provider
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStaticAt(t *testing.T, params map[string]string, now time.Time) CarbonIntensityProvider {
+	t.Helper()
+	p, err := New(Static, Options{Params: params})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	p.(*static).now = func() time.Time { return now }
+	return p
+}
+
+// hourlyProfile returns the values base, base+1, ... base+23.
+func hourlyProfile(base float64) string {
+	values := make([]string, 24)
+	for h := range values {
+		values[h] = strconv.FormatFloat(base+float64(h), 'f', -1, 64)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestStatic_Value(t *testing.T) {
+	p := newStaticAt(t, map[string]string{"value": "250"}, time.Now())
+
+	ci, err := p.Fetch(context.Background(), "TW")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if ci.Value != 250 || ci.Source != Static || ci.Unit != UnitGramsPerKWh {
+		t.Fatalf("unexpected intensity: %+v", ci)
+	}
+}
+
+func TestStatic_Profiles(t *testing.T) {
+	params := map[string]string{
+		"timezone": "Asia/Taipei",
+		"hourly":   hourlyProfile(100),
+		"sunday":   hourlyProfile(500),
+		"value":    "1",
+	}
+
+	// Saturday 23:30 UTC is Sunday 07:30 in Taipei
+	p := newStaticAt(t, params, time.Date(2025, 5, 24, 23, 30, 0, 0, time.UTC))
+	ci, err := p.Fetch(context.Background(), "TW")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if ci.Value != 507 {
+		t.Fatalf("unexpected day of week intensity: got %v want %v", ci.Value, 507)
+	}
+
+	// Saturday 01:00 UTC is Saturday 09:00 in Taipei, without a saturday profile
+	p = newStaticAt(t, params, time.Date(2025, 5, 24, 1, 0, 0, 0, time.UTC))
+	if ci, _ = p.Fetch(context.Background(), "TW"); ci.Value != 109 {
+		t.Fatalf("unexpected hourly intensity: got %v want %v", ci.Value, 109)
+	}
+}
+
+func TestStatic_CSV(t *testing.T) {
+	params := map[string]string{"csv": `timestamp,value
+2025-05-21T11:00:00Z,320
+2025-05-21T10:00:00Z,300
+`}
+
+	p := newStaticAt(t, params, time.Date(2025, 5, 21, 10, 30, 0, 0, time.UTC))
+	ci, err := p.Fetch(context.Background(), "TW")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if ci.Value != 300 || !ci.Timestamp.Equal(time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected intensity: %+v", ci)
+	}
+
+	p = newStaticAt(t, params, time.Date(2025, 5, 21, 9, 0, 0, 0, time.UTC))
+	if _, err := p.Fetch(context.Background(), "TW"); err == nil {
+		t.Fatalf("expected an error before the start of the series")
+	}
+}
+
+func TestStatic_InvalidOptions(t *testing.T) {
+	for _, params := range []map[string]string{
+		{},
+		{"hourly": "1,2,3"},
+		{"value": "1", "timezone": "Mars/Olympus"},
+		{"csv": "2025-05-21T10:00:00Z,300\nnot-a-time,1"},
+	} {
+		if _, err := New(Static, Options{Params: params}); err == nil {
+			t.Fatalf("expected an error for options %v", params)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"sustain_kube/internal/controller/retry"
+)
+
+const (
+	// WattTime is the registry name of the WattTime provider.
+	WattTime = "watttime"
+
+	wattTimeURL = "https://api.watttime.org"
+	// wattTimeSignalType is the marginal operating emissions rate signal
+	wattTimeSignalType = "co2_moer"
+	// wattTimeTokenTTL is how long a login token is reused, WattTime tokens expire after 30 minutes
+	wattTimeTokenTTL = 25 * time.Minute
+	// gramsPerPound converts the lbs CO2/MWh of WattTime to g/kWh
+	gramsPerPound = 453.59237
+)
+
+func init() {
+	Register(WattTime, newWattTime)
+}
+
+// errUnauthorized is returned when WattTime rejects the bearer token.
+var errUnauthorized = errors.New("unauthorized")
+
+// wattTimeTokens caches login tokens across provider instances, keyed by base URL, username and a hash of
+// the password. Concurrent logins of the same credentials are coalesced, the lock only guards the map.
+var wattTimeTokens = struct {
+	sync.Mutex
+	tokens map[string]wattTimeToken
+	logins singleflight.Group
+}{tokens: map[string]wattTimeToken{}}
+
+type wattTimeToken struct {
+	value   string
+	expires time.Time
+}
+
+// wattTime fetches the marginal operating emissions rate (MOER), its signal index and its forecast
+// from the WattTime v3 API. The password is the token of the estimator's Secret.
+//
+// Supported options:
+//
+//	url:       endpoint to query instead of the public API
+//	username:  WattTime account name (required)
+//	region:    WattTime region (e.g. CAISO_NORTH), defaults to the estimator zone
+//	latitude:  with longitude, looks the region up from a location instead
+//	longitude: see latitude
+type wattTime struct {
+	url       *url.URL
+	username  string
+	password  string
+	region    string
+	latitude  string
+	longitude string
+	client    *http.Client
+}
+
+func newWattTime(opts Options) (CarbonIntensityProvider, error) {
+	targetURL := opts.Params["url"]
+	if targetURL == "" {
+		targetURL = wattTimeURL
+	}
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid watttime url: %w", err)
+	}
+
+	if opts.Params["username"] == "" {
+		return nil, fmt.Errorf("the watttime provider requires the username option")
+	}
+	if (opts.Params["latitude"] == "") != (opts.Params["longitude"] == "") {
+		return nil, fmt.Errorf("the watttime latitude and longitude options must be set together")
+	}
+
+	return &wattTime{
+		url:       u,
+		username:  opts.Params["username"],
+		password:  opts.Token,
+		region:    opts.Params["region"],
+		latitude:  opts.Params["latitude"],
+		longitude: opts.Params["longitude"],
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+func (w *wattTime) Fetch(ctx context.Context, zone string) (CarbonIntensity, error) {
+	ci, err := w.fetch(ctx, zone)
+	if errors.Is(err, errUnauthorized) {
+		// the cached token was revoked or expired early, log in again once
+		w.forgetToken()
+		ci, err = w.fetch(ctx, zone)
+	}
+	return ci, err
+}
+
+func (w *wattTime) fetch(ctx context.Context, zone string) (CarbonIntensity, error) {
+	token, err := w.token(ctx)
+	if err != nil {
+		return CarbonIntensity{}, err
+	}
+
+	region, err := w.resolveRegion(ctx, token, zone)
+	if err != nil {
+		return CarbonIntensity{}, err
+	}
+
+	params := url.Values{"region": {region}, "signal_type": {wattTimeSignalType}}
+
+	// https://docs.watttime.org/#tag/GET-Forecast/operation/get_forecast_v3_forecast_get
+	var forecast wattTimeData
+	params.Set("horizon_hours", "0")
+	if err := w.get(ctx, token, "/v3/forecast", params, &forecast); err != nil {
+		return CarbonIntensity{}, fmt.Errorf("unable to fetch watttime marginal emissions: %w", err)
+	}
+	if len(forecast.Data) == 0 {
+		return CarbonIntensity{}, fmt.Errorf("no watttime marginal emissions for region %s", region)
+	}
+
+	// https://docs.watttime.org/#tag/GET-Index/operation/get_signal_index_v3_signal_index_get
+	var index wattTimeData
+	params.Del("horizon_hours")
+	if err := w.get(ctx, token, "/v3/signal-index", params, &index); err != nil {
+		return CarbonIntensity{}, fmt.Errorf("unable to fetch watttime signal index: %w", err)
+	}
+
+	current := forecast.Data[0]
+	ci := CarbonIntensity{
+		Value:     current.Value * gramsPerPound / 1000,
+		Unit:      UnitGramsPerKWh,
+		Timestamp: time.Now(),
+		Source:    WattTime,
+		Signal:    SignalMarginal,
+	}
+	if t, err := time.Parse(time.RFC3339, current.PointTime); err == nil {
+		ci.Timestamp = t
+	}
+	if len(index.Data) > 0 {
+		ci.Index = strconv.FormatFloat(index.Data[0].Value, 'f', -1, 64)
+	}
+
+	return ci, nil
+}
+
+// Forecast returns the marginal emissions forecast of the region, in 5 minute periods.
+func (w *wattTime) Forecast(ctx context.Context, zone string, horizon time.Duration) ([]ForecastPoint, error) {
+	points, err := w.forecast(ctx, zone, horizon)
+	if errors.Is(err, errUnauthorized) {
+		w.forgetToken()
+		points, err = w.forecast(ctx, zone, horizon)
+	}
+	return points, err
+}
+
+func (w *wattTime) forecast(ctx context.Context, zone string, horizon time.Duration) ([]ForecastPoint, error) {
+	token, err := w.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := w.resolveRegion(ctx, token, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"region":        {region},
+		"signal_type":   {wattTimeSignalType},
+		"horizon_hours": {strconv.Itoa(int(math.Ceil(horizon.Hours())))},
+	}
+	var forecast wattTimeData
+	if err := w.get(ctx, token, "/v3/forecast", params, &forecast); err != nil {
+		return nil, fmt.Errorf("unable to fetch watttime marginal emissions forecast: %w", err)
+	}
+
+	points := make([]ForecastPoint, 0, len(forecast.Data))
+	for _, point := range forecast.Data {
+		t, err := time.Parse(time.RFC3339, point.PointTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watttime forecast point time %q: %w", point.PointTime, err)
+		}
+		points = append(points, ForecastPoint{Timestamp: t, Value: point.Value * gramsPerPound / 1000})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+
+	now := time.Now()
+	return within(points, now, now.Add(horizon), 5*time.Minute), nil
+}
+
+// wattTimeData is the payload of the WattTime data endpoints.
+type wattTimeData struct {
+	Data []struct {
+		PointTime string  `json:"point_time"`
+		Value     float64 `json:"value"`
+	} `json:"data"`
+}
+
+// resolveRegion returns the configured region, the region of the configured location, or the zone.
+func (w *wattTime) resolveRegion(ctx context.Context, token, zone string) (string, error) {
+	if w.region != "" {
+		return w.region, nil
+	}
+	if w.latitude == "" {
+		return zone, nil
+	}
+
+	// https://docs.watttime.org/#tag/GET-Regions-and-Maps/operation/get_reg_loc_v3_region_from_loc_get
+	var result struct {
+		Region string `json:"region"`
+	}
+	params := url.Values{
+		"latitude":    {w.latitude},
+		"longitude":   {w.longitude},
+		"signal_type": {wattTimeSignalType},
+	}
+	if err := w.get(ctx, token, "/v3/region-from-loc", params, &result); err != nil {
+		return "", fmt.Errorf("unable to look up the watttime region: %w", err)
+	}
+	if result.Region == "" {
+		return "", fmt.Errorf("no watttime region at %s,%s", w.latitude, w.longitude)
+	}
+	return result.Region, nil
+}
+
+// tokenKey identifies the login token of the credentials of the provider.
+func (w *wattTime) tokenKey() string {
+	sum := sha256.Sum256([]byte(w.password))
+	return w.url.String() + "|" + w.username + "|" + hex.EncodeToString(sum[:])
+}
+
+// token returns a cached login token, logging in with basic auth when there is none or it expired.
+func (w *wattTime) token(ctx context.Context) (string, error) {
+	key := w.tokenKey()
+
+	wattTimeTokens.Lock()
+	cached, ok := wattTimeTokens.tokens[key]
+	wattTimeTokens.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.value, nil
+	}
+
+	token, err, _ := wattTimeTokens.logins.Do(key, func() (interface{}, error) {
+		token, err := w.login(ctx)
+		if err != nil {
+			return "", err
+		}
+		wattTimeTokens.Lock()
+		wattTimeTokens.tokens[key] = wattTimeToken{value: token, expires: time.Now().Add(wattTimeTokenTTL)}
+		wattTimeTokens.Unlock()
+		return token, nil
+	})
+	return token.(string), err
+}
+
+// login requests a new token. Rejected credentials are permanent, they are not retried before the
+// Secret changes.
+func (w *wattTime) login(ctx context.Context) (string, error) {
+	// https://docs.watttime.org/#tag/Authentication/operation/get_token_login_get
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url.JoinPath("/login").String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(w.username, w.password)
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := w.do(req, &result); err != nil {
+		err = fmt.Errorf("watttime login failed: %w", err)
+		var statusErr *retry.StatusError
+		if errors.As(err, &statusErr) &&
+			(statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+			return "", retry.Permanent(err)
+		}
+		return "", err
+	}
+	if result.Token == "" {
+		return "", fmt.Errorf("watttime login returned no token")
+	}
+	return result.Token, nil
+}
+
+func (w *wattTime) forgetToken() {
+	wattTimeTokens.Lock()
+	defer wattTimeTokens.Unlock()
+	delete(wattTimeTokens.tokens, w.tokenKey())
+}
+
+// get sends an authenticated GET request and decodes the JSON response into out.
+func (w *wattTime) get(ctx context.Context, token, path string, params url.Values, out interface{}) error {
+	u := w.url.JoinPath(path)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	err = w.do(req, out)
+	var statusErr *retry.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized {
+		// the bearer token was revoked or expired early
+		return fmt.Errorf("%w: %w", errUnauthorized, err)
+	}
+	return err
+}
+
+func (w *wattTime) do(req *http.Request, out interface{}) error {
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Log.Error(err, "Error closing watttime API response body")
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read watttime response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return retry.NewStatusError(resp.StatusCode, "watttime API error: %s", string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse watttime JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+//go:build unit
+// +build unit
+
+package provider
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sustain_kube/internal/controller/retry"
+)
+
+// fakeWattTime serves the WattTime v3 endpoints used by the provider, counting the login attempts.
+// Tokens issued before revoke is set are rejected afterwards.
+func fakeWattTime(t *testing.T, logins *int32, revoke *atomic.Bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/login" {
+			n := atomic.AddInt32(logins, 1)
+			if user, password, ok := r.BasicAuth(); !ok || user != "alice" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"token-` + string(rune('0'+n)) + `"}`))
+			return
+		}
+
+		token := r.Header.Get("Authorization")
+		if token == "" || (revoke.Load() && token == "Bearer token-1") {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid token"}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v3/region-from-loc":
+			if r.URL.Query().Get("latitude") != "37.77" || r.URL.Query().Get("signal_type") != "co2_moer" {
+				t.Errorf("unexpected region lookup: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"region":"CAISO_NORTH","region_full_name":"California ISO Northern","signal_type":"co2_moer"}`))
+		case "/v3/forecast":
+			if r.URL.Query().Get("region") != "CAISO_NORTH" {
+				t.Errorf("unexpected forecast region: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"data":[{"point_time":"2025-05-21T10:00:00+00:00","value":1000}],"meta":{"units":"lbs_co2_per_mwh"}}`))
+		case "/v3/signal-index":
+			_, _ = w.Write([]byte(`{"data":[{"point_time":"2025-05-21T10:00:00+00:00","value":44}],"meta":{"units":"percentile"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestWattTime_Fetch(t *testing.T) {
+	var logins int32
+	var revoke atomic.Bool
+	ts := fakeWattTime(t, &logins, &revoke)
+	defer ts.Close()
+
+	p, err := New(WattTime, Options{Token: "secret", Params: map[string]string{
+		"url": ts.URL, "username": "alice", "latitude": "37.77", "longitude": "-122.42",
+	}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ci, err := p.Fetch(context.Background(), "US-CAL-CISO")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	// 1000 lbs/MWh = 453.59237 g/kWh
+	if math.Abs(ci.Value-453.59237) > 1e-9 || ci.Unit != UnitGramsPerKWh {
+		t.Fatalf("unexpected intensity: %v %s", ci.Value, ci.Unit)
+	}
+	if ci.Signal != SignalMarginal || ci.Index != "44" || ci.Source != WattTime {
+		t.Fatalf("unexpected signal/index/source: %q %q %q", ci.Signal, ci.Index, ci.Source)
+	}
+	if want := time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC); !ci.Timestamp.Equal(want) {
+		t.Fatalf("unexpected timestamp: got %v want %v", ci.Timestamp, want)
+	}
+
+	// the token is cached across provider instances
+	p, _ = New(WattTime, Options{Token: "secret", Params: map[string]string{"url": ts.URL, "username": "alice", "region": "CAISO_NORTH"}})
+	if _, err := p.Fetch(context.Background(), "US-CAL-CISO"); err != nil {
+		t.Fatalf("second Fetch failed: %v", err)
+	}
+	if logins != 1 {
+		t.Fatalf("expected a single login, got %d", logins)
+	}
+
+	// a rejected token triggers a new login
+	revoke.Store(true)
+	if _, err := p.Fetch(context.Background(), "US-CAL-CISO"); err != nil {
+		t.Fatalf("Fetch after token revocation failed: %v", err)
+	}
+	if logins != 2 {
+		t.Fatalf("expected a second login, got %d", logins)
+	}
+}
+
+func TestWattTime_LoginFailure(t *testing.T) {
+	var logins int32
+	var revoke atomic.Bool
+	ts := fakeWattTime(t, &logins, &revoke)
+	defer ts.Close()
+
+	p, err := New(WattTime, Options{Token: "wrong", Params: map[string]string{"url": ts.URL, "username": "alice"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	_, err = p.Fetch(context.Background(), "CAISO_NORTH")
+	if err == nil || !retry.IsPermanent(err) {
+		t.Fatalf("expected a permanent error for invalid credentials, got %v", err)
+	}
+	// rejected credentials are not retried with a new login
+	if logins != 1 {
+		t.Fatalf("expected a single login attempt, got %d", logins)
+	}
+
+	// the token of other credentials of the same user is not reused
+	p, _ = New(WattTime, Options{Token: "secret", Params: map[string]string{"url": ts.URL, "username": "alice", "region": "CAISO_NORTH"}})
+	if _, err := p.Fetch(context.Background(), "CAISO_NORTH"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	p, _ = New(WattTime, Options{Token: "wrong", Params: map[string]string{"url": ts.URL, "username": "alice", "region": "CAISO_NORTH"}})
+	if _, err := p.Fetch(context.Background(), "CAISO_NORTH"); err == nil {
+		t.Fatalf("expected the cached token not to be used with a wrong password")
+	}
+	if logins != 3 {
+		t.Fatalf("expected a login per password, got %d", logins)
+	}
+}
+
+func TestWattTime_LoginDoesNotBlockOtherCredentials(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	var logins int32
+	var revoke atomic.Bool
+	ts := fakeWattTime(t, &logins, &revoke)
+	defer ts.Close()
+
+	blocked, _ := New(WattTime, Options{Token: "secret", Params: map[string]string{"url": slow.URL, "username": "alice", "region": "CAISO_NORTH"}})
+	go func() { _, _ = blocked.Fetch(context.Background(), "CAISO_NORTH") }()
+
+	p, _ := New(WattTime, Options{Token: "secret", Params: map[string]string{"url": ts.URL, "username": "alice", "region": "CAISO_NORTH"}})
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Fetch(context.Background(), "CAISO_NORTH")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the login not to wait for the pending login of other credentials")
+	}
+}
+
+func TestWattTime_RequiresUsername(t *testing.T) {
+	if _, err := New(WattTime, Options{Token: "secret"}); err == nil {
+		t.Fatalf("expected an error without username")
+	}
+}
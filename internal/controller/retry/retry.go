@@ -0,0 +1,98 @@
+// Package retry classifies the errors of a reconcile as transient or permanent, and computes the
+// exponential backoff of estimators that keep failing.
+package retry
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// StatusError is an unexpected HTTP status returned by Prometheus or a carbon intensity API.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// NewStatusError returns a StatusError of the given status code with a formatted message.
+func NewStatusError(statusCode int, format string, args ...any) error {
+	return &StatusError{StatusCode: statusCode, Message: fmt.Sprintf(format, args...)}
+}
+
+// permanentError marks an error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as permanent: retrying cannot succeed until the spec of the estimator or an
+// object it references changes. A nil err stays nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err is permanent. Errors marked by Permanent and HTTP statuses rejecting
+// the request itself (bad request, unauthorized, forbidden, not found, unprocessable) are permanent.
+// Everything else, e.g. timeouts, refused connections, 429 or 5xx, is transient. A joined error is
+// permanent only when all of its errors are, so that a fallback chain of providers is retried as long
+// as one of them failed transiently.
+func IsPermanent(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case *permanentError:
+		return true
+	case *StatusError:
+		return isPermanentStatus(e.StatusCode)
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		for _, err := range errs {
+			if !IsPermanent(err) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	case interface{ Unwrap() error }:
+		return IsPermanent(e.Unwrap())
+	}
+	return false
+}
+
+func isPermanentStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusNotFound, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// Backoff is the delay before retrying an estimator after consecutive failed reconciles, Base doubled
+// at every failure up to Max.
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns the delay after the given number of consecutive failures.
+func (b Backoff) Delay(failures int32) time.Duration {
+	delay := b.Base
+	for i := int32(1); i < failures && delay < b.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.Max)
+}
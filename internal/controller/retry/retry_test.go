@@ -0,0 +1,60 @@
+//go:build unit
+// +build unit
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsPermanent(t *testing.T) {
+	unauthorized := NewStatusError(http.StatusUnauthorized, "carbon intensity API error: invalid token")
+	unavailable := NewStatusError(http.StatusServiceUnavailable, "received non-200 response code: 503")
+	timeout := errors.New("request failed: context deadline exceeded")
+
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"nil", nil, false},
+		{"plain error", timeout, false},
+		{"marked permanent", Permanent(timeout), true},
+		{"wrapped unauthorized", fmt.Errorf("electricitymaps: %w", unauthorized), true},
+		{"service unavailable", unavailable, false},
+		{"too many requests", NewStatusError(http.StatusTooManyRequests, "rate limited"), false},
+		{"every provider permanent", errors.Join(unauthorized, Permanent(timeout)), true},
+		{"one provider transient", fmt.Errorf("every provider failed: %w", errors.Join(unauthorized, timeout)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPermanent(tt.err); got != tt.permanent {
+				t.Fatalf("IsPermanent(%v) = %v, want %v", tt.err, got, tt.permanent)
+			}
+		})
+	}
+
+	if Permanent(nil) != nil {
+		t.Fatalf("expected a nil error to stay nil")
+	}
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Base: 30 * time.Second, Max: 10 * time.Minute}
+
+	want := []time.Duration{30 * time.Second, 30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute,
+		8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for failures, delay := range want {
+		if got := b.Delay(int32(failures)); got != delay {
+			t.Fatalf("unexpected delay after %d failures: got %v want %v", failures, got, delay)
+		}
+	}
+
+	if got := b.Delay(1000); got != b.Max {
+		t.Fatalf("unexpected delay after many failures: %v", got)
+	}
+}
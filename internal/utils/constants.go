@@ -1,11 +1,73 @@
 package utils
 
+import "time"
+
 const (
-	// ErrorInt is the error value for the carbon estimator
+	// ErrorInt is the value older controllers wrote to the measurements of the status on errors
 	ErrorInt = "-1"
 
 	ErrorStatus    = "Error"
 	NormalStatus   = "Normal"
 	WarningStatus  = "Warning"
 	CriticalStatus = "Critical"
+
+	// DefaultProvider is the carbon intensity provider used when spec.provider is unset
+	DefaultProvider = "electricitymaps"
+	// DefaultPowerQuery is the PromQL query measuring the power consumption when powerMetricQuery is empty,
+	// assuming an exporter exposes node_power_watts
+	DefaultPowerQuery = "sum(node_power_watts)"
+	// DefaultRegionLabel is the node label holding the region nodes are mapped to grid zones by
+	DefaultRegionLabel = "topology.kubernetes.io/region"
+	// DefaultSecretKey is the key of the API token within the referenced Secret
+	DefaultSecretKey = "token"
+	// DefaultReconcileInterval is the interval between two reconciles when neither spec.interval nor
+	// the manager flag set one
+	DefaultReconcileInterval = 5 * time.Minute
+	// MinReconcileInterval is the shortest interval between two reconciles of an estimator
+	MinReconcileInterval = 30 * time.Second
+	// ReconcileJitter is the fraction of the interval a reconcile is delayed by at most
+	ReconcileJitter = 0.1
+	// RetryBackoffBase is the delay before retrying an estimator after a failed reconcile, doubled at every
+	// consecutive failure of a transient error
+	RetryBackoffBase = 30 * time.Second
+	// RetryBackoffMax caps the delay between retries, permanent errors are retried after it
+	RetryBackoffMax = 30 * time.Minute
+	// DefaultIntensityMaxAge is how long the last known good carbon intensity is used by default
+	DefaultIntensityMaxAge = time.Hour
+	// DefaultForecastHorizon is how far ahead the carbon intensity forecast is retrieved by default
+	DefaultForecastHorizon = 24 * time.Hour
+	// DefaultForecastHistoryDays is how many days of intensity history the built-in forecast uses by default
+	DefaultForecastHistoryDays = 7
+	// ForecastSourceBuiltin is the forecast source reported when the operator estimates the forecast itself
+	ForecastSourceBuiltin = "builtin"
+	// IntensitySourceLastKnownGood is the intensity source reported when every provider failed
+	IntensitySourceLastKnownGood = "lastKnownGood"
+
+	AttributionNone      = "None"
+	AttributionNamespace = "Namespace"
+	AttributionWorkload  = "Workload"
+
+	PowerSourceModel  = "model"
+	PowerSourceKepler = "kepler"
+
+	ForecastModelSeasonalNaive = "seasonalNaive"
+	ForecastModelHoltWinters   = "holtWinters"
+	ForecastModelNone          = "none"
+
+	PowerModelQuery        = "query"
+	PowerModelCoefficients = "coefficients"
+	PowerModelIdleMax      = "idleMax"
+	// DefaultNodeLabel is the Prometheus label holding the node of cAdvisor series
+	DefaultNodeLabel = "node"
+	// DefaultNodeExporterLabel is the Prometheus label holding the node of node-exporter series
+	DefaultNodeExporterLabel = "instance"
+	// DefaultCatalogKey is the ConfigMap key holding an instance type catalog
+	DefaultCatalogKey = "catalog.yaml"
+	// DefaultEmbodiedKey is the ConfigMap key holding the embodied emissions of instance types
+	DefaultEmbodiedKey = "embodied.yaml"
+
+	// DefaultCPUWeight is the percentage of power attributed by CPU usage
+	DefaultCPUWeight = 50
+	// DefaultTopN is the number of attribution entries kept in status
+	DefaultTopN = 10
 )
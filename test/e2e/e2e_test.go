@@ -498,7 +498,9 @@ spec:
   powerConsumptionMemory: "1.5"
   levelWarning: 5000
   levelCritical: 10000
-`, estimatorName, namespace, mockPromURL)
+  secretRef:
+    name: %s
+`, estimatorName, namespace, mockPromURL, secretName)
 
 				tmpFile := filepath.Join(os.TempDir(), "sustain_kube_happy_cr.yaml")
 				Expect(os.WriteFile(tmpFile, []byte(crYAML), 0644)).To(Succeed())
@@ -527,7 +529,8 @@ spec:
 						"-o", "jsonpath={.status.emission}")
 					output, err := utils.Run(cmd)
 					g.Expect(err).NotTo(HaveOccurred())
-					g.Expect(output).To(ContainSubstring("495000"), "Expected emission to be 495000, got %s", output)
+					// 1650 W * 300 gCO2eq/kWh = 495 gCO2eq/h
+					g.Expect(output).To(Equal("495.00"), "Expected emission to be 495.00, got %s", output)
 				}
 				Eventually(verifyEmission, 2*time.Minute, 1*time.Second).Should(Succeed())
 			})